@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	key       string
+	value     V
+	storedAt  time.Time
+	expiresAt time.Time
+	size      int
+}
+
+// Entry is a cached value with its age information, as returned by Lookup.
+type Entry[V any] struct {
+	Value    V
+	StoredAt time.Time
+	Stale    bool
+}
+
+// LRU is a fixed-size, thread-safe least-recently-used cache with a per-entry TTL.
+type LRU[V any] struct {
+	mu    sync.Mutex
+	max   int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+
+	maxBytes int
+	bytes    int
+	size     func(V) int
+}
+
+func New[V any](maxEntries int, ttl time.Duration) *LRU[V] {
+	return &LRU[V]{
+		max:   maxEntries,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// NewSized is New with a budget of maxBytes on top, as measured by size, for
+// values whose size varies too much for an entry count to bound memory.
+// Values larger than the whole budget aren't stored.
+func NewSized[V any](maxEntries, maxBytes int, ttl time.Duration, size func(V) int) *LRU[V] {
+	c := New[V](maxEntries, ttl)
+	c.maxBytes = maxBytes
+	c.size = size
+	return c
+}
+
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := el.Value.(*entry[V])
+	if time.Now().After(e.expiresAt) {
+		c.remove(el)
+		return zero, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Lookup returns an entry even if it has expired, flagged as Stale, so callers
+// can serve it while refreshing in the background.
+func (c *LRU[V]) Lookup(key string) (Entry[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return Entry[V]{}, false
+	}
+
+	e := el.Value.(*entry[V])
+	c.ll.MoveToFront(el)
+	return Entry[V]{Value: e.value, StoredAt: e.storedAt, Stale: time.Now().After(e.expiresAt)}, true
+}
+
+func (c *LRU[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := 0
+	if c.size != nil {
+		size = c.size(value)
+		if size > c.maxBytes {
+			return
+		}
+	}
+
+	now := time.Now()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		c.bytes += size - e.size
+		e.value = value
+		e.size = size
+		e.storedAt = now
+		e.expiresAt = now.Add(c.ttl)
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(&entry[V]{key: key, value: value, storedAt: now, expiresAt: now.Add(c.ttl), size: size})
+		c.bytes += size
+	}
+
+	for (c.max > 0 && c.ll.Len() > c.max) || (c.size != nil && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *LRU[V]) remove(el *list.Element) {
+	e := el.Value.(*entry[V])
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
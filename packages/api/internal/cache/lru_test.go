@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := New[int](2, time.Hour)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a=1, got %d, %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c := New[string](10, time.Millisecond)
+	c.Set("k", "v")
+	time.Sleep(5 * time.Millisecond)
+
+	e, ok := c.Lookup("k")
+	if !ok || !e.Stale || e.Value != "v" {
+		t.Errorf("expected stale entry from Lookup, got %+v, %v", e, ok)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("expected Get to drop expired entry")
+	}
+	if _, ok := c.Lookup("k"); ok {
+		t.Error("expected entry to be gone after Get")
+	}
+}
+
+func TestLRUByteBudget(t *testing.T) {
+	c := NewSized[string](10, 8, time.Hour, func(v string) int { return len(v) })
+	c.Set("a", "1234")
+	c.Set("b", "1234")
+	c.Set("c", "12")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected oldest entry to be evicted over budget")
+	}
+	c.Set("b", "1")
+	c.Set("d", "12345")
+	if c.Len() != 3 {
+		t.Errorf("expected 3 entries, got %d", c.Len())
+	}
+	c.Set("e", "123456789")
+	if _, ok := c.Get("e"); ok {
+		t.Error("expected value over the whole budget not to be stored")
+	}
+}
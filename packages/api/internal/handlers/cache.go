@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dnl-fm/md/packages/api/internal/cache"
+	"github.com/dnl-fm/md/packages/api/internal/renderer"
+)
+
+type cachedRender struct {
+	body     []byte
+	duration time.Duration
+}
+
+type renderFunc func(ctx context.Context) ([]byte, error)
+
+var (
+	// renderCache holds up to 2000 renders, but no more than 128 MB of them,
+	// since large PNGs dwarf typical SVGs.
+	renderCache = cache.NewSized(2000, 128<<20, 24*time.Hour, func(c cachedRender) int { return len(c.body) })
+	refreshing  sync.Map
+
+	// failureCache remembers diagrams the renderer rejected, so a broken
+	// diagram embedded on a busy page doesn't take a browser slot per view.
+	// Keys include the renderer version, so upgrades retry them.
+	failureCache = cache.New[error](2000, 10*time.Minute)
+)
+
+// renderWithCache serves key from the in-memory render cache, rendering on a
+// miss. Stale entries are served immediately and refreshed in the background.
+// It sets X-Cache-Status, X-Render-Duration (ms) and Age on w, and also
+// returns how long the (original) render took. Renders that fail with a
+// renderer.RenderError are remembered briefly and fail again from cache
+// with X-Cache-Status: ERROR.
+func renderWithCache(ctx context.Context, w http.ResponseWriter, key string, render renderFunc) ([]byte, time.Duration, error) {
+	if e, ok := renderCache.Lookup(key); ok {
+		status := "HIT"
+		if e.Stale {
+			status = "STALE"
+			refreshInBackground(key, render)
+		}
+		setCacheHeaders(w, status, e.Value.duration, time.Since(e.StoredAt))
+		return e.Value.body, e.Value.duration, nil
+	}
+	if err, ok := failureCache.Get(key); ok {
+		w.Header().Set("X-Cache-Status", "ERROR")
+		return nil, 0, err
+	}
+
+	body, duration, err := renderAndStore(ctx, key, render)
+	if err != nil {
+		return nil, 0, err
+	}
+	setCacheHeaders(w, "MISS", duration, 0)
+	return body, duration, nil
+}
+
+// renderAndStore renders key into the render cache, or into failureCache
+// when the renderer rejects the source.
+func renderAndStore(ctx context.Context, key string, render renderFunc) ([]byte, time.Duration, error) {
+	start := time.Now()
+	body, err := render(ctx)
+	if err != nil {
+		var renderErr *renderer.RenderError
+		if errors.As(err, &renderErr) {
+			failureCache.Set(key, err)
+		}
+		return nil, 0, err
+	}
+	duration := time.Since(start)
+
+	renderCache.Set(key, cachedRender{body: body, duration: duration})
+	return body, duration, nil
+}
+
+func refreshInBackground(key string, render renderFunc) {
+	if _, running := refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer refreshing.Delete(key)
+
+		start := time.Now()
+		body, err := render(context.Background())
+		if err != nil {
+			log.Printf("background refresh of %s failed: %v", key, err)
+			return
+		}
+		renderCache.Set(key, cachedRender{body: body, duration: time.Since(start)})
+	}()
+}
+
+func setCacheHeaders(w http.ResponseWriter, status string, duration, age time.Duration) {
+	w.Header().Set("X-Cache-Status", status)
+	w.Header().Set("X-Render-Duration", strconv.FormatInt(duration.Milliseconds(), 10))
+	w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+}
@@ -7,34 +7,95 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dnl-fm/md/packages/api/internal/renderer"
 	"github.com/go-chi/chi/v5"
 )
 
-var mermaidRenderer *renderer.MermaidRenderer
+const maxBodyBytes = 1 << 20
+
+var (
+	browser          *renderer.Browser
+	mermaidRenderer  *renderer.MermaidRenderer // default version
+	mermaidRenderers map[string]*renderer.MermaidRenderer
+	vegaLiteRenderer *renderer.VegaLiteRenderer
+	waveDromRenderer *renderer.WaveDromRenderer
+	nomnomlRenderer  *renderer.NomnomlRenderer
+	renderLimits     renderer.Limits
+)
+
+// InitializeRenderers starts the browser and warms up all renderers. Each
+// mermaid version in extraMermaidVersions gets its own page next to the
+// default renderer.MermaidVersion.
+func InitializeRenderers(limits renderer.Limits, extraMermaidVersions []string) error {
+	renderLimits = limits
 
-func InitializeRenderers() error {
 	var err error
-	mermaidRenderer, err = renderer.NewMermaidRenderer()
+	browser, err = renderer.NewBrowser()
 	if err != nil {
-		return fmt.Errorf("failed to initialize mermaid renderer: %w", err)
+		return err
+	}
+
+	mermaidRenderers = make(map[string]*renderer.MermaidRenderer)
+	for _, version := range append([]string{renderer.MermaidVersion}, extraMermaidVersions...) {
+		if _, ok := mermaidRenderers[version]; ok {
+			continue
+		}
+		mr, err := renderer.NewMermaidRenderer(browser, version, limits)
+		if err != nil {
+			return fmt.Errorf("failed to initialize mermaid %s renderer: %w", version, err)
+		}
+		mermaidRenderers[version] = mr
+	}
+	mermaidRenderer = mermaidRenderers[renderer.MermaidVersion]
+
+	vegaLiteRenderer, err = renderer.NewVegaLiteRenderer(browser, limits)
+	if err != nil {
+		return fmt.Errorf("failed to initialize vega-lite renderer: %w", err)
+	}
+
+	waveDromRenderer, err = renderer.NewWaveDromRenderer(browser, limits)
+	if err != nil {
+		return fmt.Errorf("failed to initialize wavedrom renderer: %w", err)
+	}
+
+	nomnomlRenderer, err = renderer.NewNomnomlRenderer(browser, limits)
+	if err != nil {
+		return fmt.Errorf("failed to initialize nomnoml renderer: %w", err)
 	}
 	return nil
 }
 
 func CloseRenderers() {
-	if mermaidRenderer != nil {
-		mermaidRenderer.Close()
+	for _, mr := range mermaidRenderers {
+		mr.Close()
+	}
+	if vegaLiteRenderer != nil {
+		vegaLiteRenderer.Close()
+	}
+	if waveDromRenderer != nil {
+		waveDromRenderer.Close()
+	}
+	if nomnomlRenderer != nil {
+		nomnomlRenderer.Close()
+	}
+	if browser != nil {
+		browser.Close()
 	}
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// RequestID matches the X-Request-ID header and the server log line.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func Health(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +103,191 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+const (
+	cacheControl          = "public, max-age=2592000"
+	cacheControlImmutable = "public, max-age=31536000, immutable"
+)
+
+// RenderMermaid renders with the default mermaid version, or the one given
+// in ?version=.
 func RenderMermaid(w http.ResponseWriter, r *http.Request) {
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = renderer.MermaidVersion
+	}
+	mr, ok := mermaidRenderers[version]
+	if !ok {
+		respondError(w, "unsupported mermaid version, see /render/versions", http.StatusBadRequest)
+		return
+	}
+	renderMermaid(w, r, mr, cacheControl)
+}
+
+// RenderMermaidVersioned serves /render/v/{rendererVersion}/mermaid/... URLs.
+// The path pins the mermaid release, so responses never change and can be
+// cached as immutable.
+func RenderMermaidVersioned(w http.ResponseWriter, r *http.Request) {
+	mr, ok := mermaidRenderers[chi.URLParam(r, "rendererVersion")]
+	if !ok {
+		respondError(w, "unsupported renderer version, see /render/versions", http.StatusNotFound)
+		return
+	}
+	renderMermaid(w, r, mr, cacheControlImmutable)
+}
+
+// VersionsResponse lists the renderer releases this server has loaded.
+type VersionsResponse struct {
+	Mermaid        []string `json:"mermaid"`
+	MermaidDefault string   `json:"mermaid_default"`
+	VegaLite       string   `json:"vegalite"`
+	WaveDrom       string   `json:"wavedrom"`
+	Nomnoml        string   `json:"nomnoml"`
+}
+
+func RenderVersions(w http.ResponseWriter, r *http.Request) {
+	versions := make([]string, 0, len(mermaidRenderers))
+	for v := range mermaidRenderers {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	respondJSON(w, VersionsResponse{
+		Mermaid:        versions,
+		MermaidDefault: renderer.MermaidVersion,
+		VegaLite:       renderer.VegaLiteVersion,
+		WaveDrom:       renderer.WaveDromVersion,
+		Nomnoml:        renderer.NomnomlVersion,
+	})
+}
+
+func renderMermaid(w http.ResponseWriter, r *http.Request, mr *renderer.MermaidRenderer, cacheHeader string) {
+	theme := chi.URLParam(r, "theme")
+	hash := chi.URLParam(r, "hash")
+	codeB64 := r.URL.Query().Get("code")
+
+	if theme != "dark" && theme != "light" && theme != "auto" {
+		respondError(w, "invalid theme, must be 'dark', 'light' or 'auto'", http.StatusBadRequest)
+		return
+	}
+
+	code, ok := decodeCode(w, codeB64, hash)
+	if !ok {
+		return
+	}
+
+	if err := renderLimits.Check(string(code)); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vars, err := renderer.ParseThemeVariables(r.URL.Query().Get("themeVariables"))
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := renderer.MermaidOptions{ThemeVariables: vars}
+	if err := renderer.ParseDiagramConfig(r.URL.Query().Get("config"), &opts); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch font := r.URL.Query().Get("font"); font {
+	case "", "system":
+	case "embed":
+		opts.EmbedFont = true
+	default:
+		respondError(w, "invalid font, must be 'system' or 'embed'", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "svg" && format != "json" {
+		respondError(w, "invalid format, must be 'svg' or 'json'", http.StatusBadRequest)
+		return
+	}
+
+	key := mermaidCacheKey(mr, theme, hash, opts)
+	svg, duration, err := renderWithCache(r.Context(), w, key, mermaidRender(mr, string(code), theme, opts))
+	if err != nil {
+		respondRenderError(w, r, err, mr.Version())
+		return
+	}
+
+	width, height, _ := renderer.SVGSize(svg)
+	w.Header().Set("Cache-Control", cacheHeader)
+
+	if format == "json" {
+		respondJSON(w, RenderResponse{
+			SVG:             string(svg),
+			Width:           width,
+			Height:          height,
+			Theme:           theme,
+			RendererVersion: mr.Version(),
+			DurationMs:      duration.Milliseconds(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if width > 0 && height > 0 {
+		w.Header().Set("X-SVG-Width", strconv.Itoa(width))
+		w.Header().Set("X-SVG-Height", strconv.Itoa(height))
+	}
+	w.Write(svg)
+}
+
+func mermaidCacheKey(mr *renderer.MermaidRenderer, theme, hash string, opts renderer.MermaidOptions) string {
+	key := "mermaid:" + mr.Version() + ":" + theme + ":" + hash
+	if k := opts.CacheKey(); k != "" {
+		key += ":" + k
+	}
+	return key
+}
+
+func mermaidRender(mr *renderer.MermaidRenderer, code, theme string, opts renderer.MermaidOptions) renderFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		if theme == "auto" {
+			return renderAdaptive(ctx, mr, code, opts)
+		}
+		svg, err := mr.Render(ctx, code, theme, opts)
+		if err != nil {
+			return nil, err
+		}
+		return renderer.MinifySVG([]byte(svg)), nil
+	}
+}
+
+// renderAdaptive renders code in both themes and combines them into one SVG
+// that follows the reader's prefers-color-scheme.
+func renderAdaptive(ctx context.Context, mr *renderer.MermaidRenderer, code string, opts renderer.MermaidOptions) ([]byte, error) {
+	opts.ID = "diagram-light"
+	light, err := mr.Render(ctx, code, "light", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.ID = "diagram-dark"
+	dark, err := mr.Render(ctx, code, "dark", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderer.MinifySVG(renderer.CombineAdaptive([]byte(light), []byte(dark))), nil
+}
+
+// RenderResponse is the ?format=json variant of a mermaid render.
+type RenderResponse struct {
+	SVG             string `json:"svg"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	Theme           string `json:"theme"`
+	RendererVersion string `json:"renderer_version"`
+	DurationMs      int64  `json:"duration_ms"`
+}
+
+// RenderVegaLite renders a Vega-Lite JSON spec. ?format=png returns a PNG
+// instead of SVG.
+func RenderVegaLite(w http.ResponseWriter, r *http.Request) {
 	theme := chi.URLParam(r, "theme")
 	hash := chi.URLParam(r, "hash")
 	codeB64 := r.URL.Query().Get("code")
@@ -52,55 +297,200 @@ func RenderMermaid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code, err := base64.URLEncoding.DecodeString(codeB64)
+	format := r.URL.Query().Get("format")
+	contentType := "image/svg+xml"
+	switch format {
+	case "", "svg":
+		format = "svg"
+	case "png":
+		contentType = "image/png"
+	default:
+		respondError(w, "invalid format, must be 'svg' or 'png'", http.StatusBadRequest)
+		return
+	}
+
+	spec, ok := decodeCode(w, codeB64, hash)
+	if !ok {
+		return
+	}
+
+	if err := renderLimits.CheckSize(string(spec)); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(spec) {
+		respondError(w, "invalid JSON spec", http.StatusBadRequest)
+		return
+	}
+
+	key := "vegalite:" + renderer.VegaLiteVersion + ":" + theme + ":" + format + ":" + hash
+	output, _, err := renderWithCache(r.Context(), w, key, vegaLiteRender(string(spec), theme, format))
 	if err != nil {
-		code, err = base64.RawURLEncoding.DecodeString(codeB64)
-		if err != nil {
-			respondError(w, "invalid base64", http.StatusBadRequest)
-			return
+		respondRenderError(w, r, err, renderer.VegaLiteVersion)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(output)
+}
+
+func vegaLiteRender(spec, theme, format string) renderFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		out, err := vegaLiteRenderer.Render(ctx, spec, theme, format)
+		if err != nil || format != "svg" {
+			return out, err
 		}
+		return renderer.MinifySVG(out), nil
 	}
+}
 
-	computed := sha256.Sum256(code)
-	computedHash := hex.EncodeToString(computed[:])
-	if computedHash != hash {
-		respondError(w, "hash mismatch", http.StatusBadRequest)
+// RenderWaveDrom renders a WaveJSON timing diagram to SVG.
+func RenderWaveDrom(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	codeB64 := r.URL.Query().Get("code")
+
+	source, ok := decodeCode(w, codeB64, hash)
+	if !ok {
+		return
+	}
+
+	if err := renderLimits.CheckSize(string(source)); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(source) {
+		respondError(w, "invalid WaveJSON, must be strict JSON", http.StatusBadRequest)
 		return
 	}
 
-	svg, err := mermaidRenderer.Render(string(code), theme)
+	key := "wavedrom:" + renderer.WaveDromVersion + ":" + hash
+	svg, _, err := renderWithCache(r.Context(), w, key, waveDromRender(string(source)))
 	if err != nil {
-		respondError(w, fmt.Sprintf("render failed: %s", err.Error()), http.StatusBadRequest)
+		respondRenderError(w, r, err, renderer.WaveDromVersion)
 		return
 	}
 
 	w.Header().Set("Content-Type", "image/svg+xml")
-	w.Header().Set("Cache-Control", "public, max-age=2592000")
-	w.Write([]byte(svg))
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(svg)
 }
 
-func RenderASCII(w http.ResponseWriter, r *http.Request) {
+func waveDromRender(source string) renderFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		svg, err := waveDromRenderer.Render(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		return renderer.MinifySVG([]byte(svg)), nil
+	}
+}
+
+// RenderNomnoml renders a nomnoml UML diagram to SVG. The theme comes from
+// ?theme= and defaults to light.
+func RenderNomnoml(w http.ResponseWriter, r *http.Request) {
 	hash := chi.URLParam(r, "hash")
 	codeB64 := r.URL.Query().Get("code")
 
-	code, err := base64.URLEncoding.DecodeString(codeB64)
+	theme := r.URL.Query().Get("theme")
+	if theme == "" {
+		theme = "light"
+	}
+	if theme != "dark" && theme != "light" {
+		respondError(w, "invalid theme, must be 'dark' or 'light'", http.StatusBadRequest)
+		return
+	}
+
+	code, ok := decodeCode(w, codeB64, hash)
+	if !ok {
+		return
+	}
+
+	if err := renderLimits.Check(string(code)); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := "nomnoml:" + renderer.NomnomlVersion + ":" + theme + ":" + hash
+	svg, _, err := renderWithCache(r.Context(), w, key, nomnomlRender(string(code), theme))
 	if err != nil {
-		code, err = base64.RawURLEncoding.DecodeString(codeB64)
+		respondRenderError(w, r, err, renderer.NomnomlVersion)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(svg)
+}
+
+func nomnomlRender(code, theme string) renderFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		svg, err := nomnomlRenderer.Render(ctx, code, theme)
 		if err != nil {
-			respondError(w, "invalid base64", http.StatusBadRequest)
-			return
+			return nil, err
 		}
+		return renderer.MinifySVG([]byte(svg)), nil
+	}
+}
+
+type ValidateRequest struct {
+	Code string `json:"code"`
+}
+
+func ValidateMermaid(w http.ResponseWriter, r *http.Request) {
+	var req ValidateRequest
+	if !decodeJSON(w, r, &req) {
+		return
 	}
 
-	computed := sha256.Sum256(code)
-	computedHash := hex.EncodeToString(computed[:])
-	if computedHash != hash {
-		respondError(w, "hash mismatch", http.StatusBadRequest)
+	if req.Code == "" {
+		respondError(w, "code is required", http.StatusBadRequest)
+		return
+	}
+	if err := renderLimits.Check(req.Code); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	result, err := mermaidRenderer.Validate(r.Context(), req.Code)
+	if err != nil {
+		respondError(w, fmt.Sprintf("validate failed: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, result)
+}
+
+func RenderASCII(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	codeB64 := r.URL.Query().Get("code")
+
+	code, ok := decodeCode(w, codeB64, hash)
+	if !ok {
+		return
+	}
+
+	if err := renderLimits.Check(string(code)); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	output, _, err := renderWithCache(r.Context(), w, "ascii:"+hash, func(ctx context.Context) ([]byte, error) {
+		return renderASCII(ctx, code)
+	})
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(output)
+}
+
+func renderASCII(ctx context.Context, code []byte) ([]byte, error) {
 	// Execute ascii renderer with 5 second timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "ascii")
@@ -108,24 +498,106 @@ func RenderASCII(w http.ResponseWriter, r *http.Request) {
 	output, err := cmd.Output()
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			respondError(w, "render timeout: diagram too complex or has cycles", http.StatusBadRequest)
-			return
+			return nil, errors.New("render timeout: diagram too complex or has cycles")
 		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			respondError(w, fmt.Sprintf("render failed: %s", string(exitErr.Stderr)), http.StatusBadRequest)
-		} else {
-			respondError(w, fmt.Sprintf("render failed: %s", err.Error()), http.StatusBadRequest)
+			return nil, fmt.Errorf("render failed: %s", string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("render failed: %s", err.Error())
+	}
+	return output, nil
+}
+
+// decodeCode decodes the base64 code query parameter and checks it against
+// the sha256 hash from the URL, writing a 400 on failure.
+func decodeCode(w http.ResponseWriter, codeB64, hash string) ([]byte, bool) {
+	code, err := base64.URLEncoding.DecodeString(codeB64)
+	if err != nil {
+		code, err = base64.RawURLEncoding.DecodeString(codeB64)
+		if err != nil {
+			respondError(w, "invalid base64", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	computed := sha256.Sum256(code)
+	computedHash := hex.EncodeToString(computed[:])
+	if computedHash != hash {
+		respondError(w, "hash mismatch", http.StatusBadRequest)
+		return nil, false
+	}
+	return code, true
+}
+
+func respondJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respondError(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// RenderErrorResponse is the body of a failed render. Code is
+// "syntax_error" when the library located the problem in the source,
+// "render_error" for other rejected source, "timeout", or "internal_error".
+type RenderErrorResponse struct {
+	Error           string `json:"error"`
+	Code            string `json:"code"`
+	Line            int    `json:"line,omitempty"`
+	Column          int    `json:"column,omitempty"`
+	Snippet         string `json:"snippet,omitempty"`
+	RendererVersion string `json:"renderer_version"`
+	RequestID       string `json:"request_id,omitempty"`
+}
+
+// respondRenderError writes a RenderErrorResponse, or with ?on_error=image
+// an ErrorSVG placeholder. The placeholder keeps the 400 status so caches
+// treat it as an error; browsers still show it in <img> tags.
+func respondRenderError(w http.ResponseWriter, r *http.Request, err error, version string) {
+	resp := RenderErrorResponse{
+		Error:           "render failed: " + err.Error(),
+		Code:            "internal_error",
+		RendererVersion: version,
+		RequestID:       w.Header().Get("X-Request-ID"),
+	}
+
+	var renderErr *renderer.RenderError
+	switch {
+	case errors.As(err, &renderErr):
+		resp.Code = "render_error"
+		if renderErr.Line > 0 {
+			resp.Code = "syntax_error"
+		}
+		resp.Line, resp.Column, resp.Snippet = renderErr.Line, renderErr.Column, renderErr.Snippet
+	case errors.Is(err, renderer.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		resp.Code = "timeout"
+	}
+
+	if r.URL.Query().Get("on_error") == "image" {
+		title := "Diagram failed to render"
+		if resp.Line > 0 {
+			title = fmt.Sprintf("Diagram error on line %d", resp.Line)
 		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(renderer.ErrorSVG(title, strings.TrimPrefix(resp.Error, "render failed: ")))
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("Cache-Control", "public, max-age=2592000")
-	w.Write(output)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func respondError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, RequestID: w.Header().Get("X-Request-ID")})
 }
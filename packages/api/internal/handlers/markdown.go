@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dnl-fm/md/packages/api/internal/markdown"
+)
+
+type LintRequest struct {
+	Content string `json:"content"`
+	markdown.LintConfig
+}
+
+type LintResponse struct {
+	Diagnostics []markdown.Diagnostic `json:"diagnostics"`
+}
+
+func LintMarkdown(w http.ResponseWriter, r *http.Request) {
+	var req LintRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	diagnostics, err := markdown.Lint(req.Content, req.LintConfig)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, LintResponse{Diagnostics: diagnostics})
+}
+
+type FormatRequest struct {
+	Content string `json:"content"`
+	markdown.FormatOptions
+}
+
+type FormatResponse struct {
+	Content string `json:"content"`
+	Changed bool   `json:"changed"`
+}
+
+func FormatMarkdown(w http.ResponseWriter, r *http.Request) {
+	var req FormatRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	formatted, err := markdown.Format(req.Content, req.FormatOptions)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, FormatResponse{Content: formatted, Changed: formatted != req.Content})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dnl-fm/md/packages/api/internal/renderer"
+)
+
+// readySaturation is how long a renderer may have callers queued before
+// Ready reports the instance as unready.
+var readySaturation = 30 * time.Second
+
+func InitializeReadiness(saturation time.Duration) {
+	if saturation > 0 {
+		readySaturation = saturation
+	}
+}
+
+func rendererStats() []renderer.Stats {
+	var stats []renderer.Stats
+	for _, mr := range mermaidRenderers {
+		stats = append(stats, mr.Stats())
+	}
+	if vegaLiteRenderer != nil {
+		stats = append(stats, vegaLiteRenderer.Stats())
+	}
+	if waveDromRenderer != nil {
+		stats = append(stats, waveDromRenderer.Stats())
+	}
+	if nomnomlRenderer != nil {
+		stats = append(stats, nomnomlRenderer.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// saturated returns the first renderer that has had callers queued for
+// longer than readySaturation.
+func saturated(stats []renderer.Stats, now time.Time) (renderer.Stats, bool) {
+	for _, s := range stats {
+		if !s.SaturatedSince.IsZero() && now.Sub(s.SaturatedSince) > readySaturation {
+			return s, true
+		}
+	}
+	return renderer.Stats{}, false
+}
+
+// Ready is the load balancer readiness check. Unlike Health it fails with
+// 503 while a renderer is saturated, so traffic shifts to other instances.
+func Ready(w http.ResponseWriter, r *http.Request) {
+	if s, ok := saturated(rendererStats(), time.Now()); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "saturated", "renderer": s.Name})
+		return
+	}
+	respondJSON(w, map[string]string{"status": "ready"})
+}
+
+// Metrics serves renderer saturation signals in the Prometheus text format.
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	stats := rendererStats()
+	now := time.Now()
+
+	var b strings.Builder
+	gauge := func(name, help string, value func(renderer.Stats) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, s := range stats {
+			fmt.Fprintf(&b, "%s{renderer=%q} %g\n", name, s.Name, value(s))
+		}
+	}
+
+	gauge("md_render_queue_depth", "Callers waiting for a renderer page.", func(s renderer.Stats) float64 {
+		return float64(s.Queued)
+	})
+	gauge("md_render_queue_wait_p95_seconds", "95th percentile queue wait over the last 256 renders.", func(s renderer.Stats) float64 {
+		return s.QueueWaitP95.Seconds()
+	})
+	gauge("md_render_saturated_seconds", "How long callers have been queued without the queue draining.", func(s renderer.Stats) float64 {
+		if s.SaturatedSince.IsZero() {
+			return 0
+		}
+		return now.Sub(s.SaturatedSince).Seconds()
+	})
+	gauge("md_render_consecutive_failures", "Timeouts and browser errors in a row.", func(s renderer.Stats) float64 {
+		return float64(s.ConsecutiveFailures)
+	})
+
+	gauge("md_render_consecutive_restarts", "Page reloads after timeouts since the last successful render.", func(s renderer.Stats) float64 {
+		return float64(s.ConsecutiveRestarts)
+	})
+
+	fmt.Fprintf(&b, "# HELP md_render_busy_seconds_total Time spent rendering.\n# TYPE md_render_busy_seconds_total counter\n")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "md_render_busy_seconds_total{renderer=%q} %g\n", s.Name, s.BusySeconds)
+	}
+	fmt.Fprintf(&b, "# HELP md_render_restarts_total Page reloads after timeouts.\n# TYPE md_render_restarts_total counter\n")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "md_render_restarts_total{renderer=%q} %d\n", s.Name, s.Restarts)
+	}
+
+	busy := 0
+	for _, s := range stats {
+		if s.Busy {
+			busy++
+		}
+	}
+	utilization := 0.0
+	if len(stats) > 0 {
+		utilization = float64(busy) / float64(len(stats))
+	}
+	fmt.Fprintf(&b, "# HELP md_render_pool_utilization Share of renderer pages busy right now.\n# TYPE md_render_pool_utilization gauge\n")
+	fmt.Fprintf(&b, "md_render_pool_utilization %g\n", utilization)
+
+	ready := 1
+	if _, ok := saturated(stats, now); ok {
+		ready = 0
+	}
+	fmt.Fprintf(&b, "# HELP md_ready Whether /readyz reports the instance as ready.\n# TYPE md_ready gauge\n")
+	fmt.Fprintf(&b, "md_ready %d\n", ready)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/dnl-fm/md/packages/api/internal/markdown"
+	"github.com/dnl-fm/md/packages/api/internal/renderer"
+)
+
+const (
+	// maxPrimeJobs caps how many renders one document may queue, so a
+	// share full of diagrams can't tie up the renderers.
+	maxPrimeJobs = 20
+	primeTimeout = 2 * time.Minute
+)
+
+// primeRenders is set by InitializeRenderPriming.
+var primeRenders bool
+
+// InitializeRenderPriming turns on pre-rendering of the diagrams in newly
+// created shares.
+func InitializeRenderPriming(enabled bool) {
+	primeRenders = enabled
+}
+
+// primeJob is one diagram to render into the cache.
+type primeJob struct {
+	key    string
+	render renderFunc
+}
+
+// primeJobs returns the cache entries the render endpoints would fill for
+// the diagram code blocks in content, in the default themes and formats.
+func primeJobs(content string) []primeJob {
+	var jobs []primeJob
+	for _, b := range markdown.CodeBlocks(content) {
+		sum := sha256.Sum256([]byte(b.Code))
+		hash := hex.EncodeToString(sum[:])
+
+		switch b.Lang {
+		case "mermaid":
+			if mermaidRenderer == nil || renderLimits.Check(b.Code) != nil {
+				continue
+			}
+			for _, theme := range []string{"light", "dark"} {
+				jobs = append(jobs, primeJob{
+					key:    mermaidCacheKey(mermaidRenderer, theme, hash, renderer.MermaidOptions{}),
+					render: mermaidRender(mermaidRenderer, b.Code, theme, renderer.MermaidOptions{}),
+				})
+			}
+		case "nomnoml":
+			if nomnomlRenderer == nil || renderLimits.Check(b.Code) != nil {
+				continue
+			}
+			jobs = append(jobs, primeJob{
+				key:    "nomnoml:" + renderer.NomnomlVersion + ":light:" + hash,
+				render: nomnomlRender(b.Code, "light"),
+			})
+		case "wavedrom":
+			if waveDromRenderer == nil || renderLimits.CheckSize(b.Code) != nil || !json.Valid([]byte(b.Code)) {
+				continue
+			}
+			jobs = append(jobs, primeJob{
+				key:    "wavedrom:" + renderer.WaveDromVersion + ":" + hash,
+				render: waveDromRender(b.Code),
+			})
+		case "vega-lite", "vegalite":
+			if vegaLiteRenderer == nil || renderLimits.CheckSize(b.Code) != nil || !json.Valid([]byte(b.Code)) {
+				continue
+			}
+			jobs = append(jobs, primeJob{
+				key:    "vegalite:" + renderer.VegaLiteVersion + ":light:svg:" + hash,
+				render: vegaLiteRender(b.Code, "light", "svg"),
+			})
+		}
+		if len(jobs) >= maxPrimeJobs {
+			return jobs[:maxPrimeJobs]
+		}
+	}
+	return jobs
+}
+
+// primeCache renders the diagrams in content into the render cache in the
+// background, one at a time, skipping ones that are already cached. It is a
+// no-op unless enabled with InitializeRenderPriming.
+func primeCache(content string) {
+	if !primeRenders {
+		return
+	}
+	jobs := primeJobs(content)
+	if len(jobs) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), primeTimeout)
+		defer cancel()
+
+		for _, job := range jobs {
+			if _, ok := renderCache.Get(job.key); ok {
+				continue
+			}
+			if _, ok := failureCache.Get(job.key); ok {
+				continue
+			}
+			if _, _, err := renderAndStore(ctx, job.key, job.render); err != nil && ctx.Err() != nil {
+				log.Printf("priming renders stopped: %v", err)
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dnl-fm/md/packages/api/internal/imageproxy"
+)
+
+var imageProxy = imageproxy.New()
+
+func ProxyImage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	opts := imageproxy.Options{Format: query.Get("format")}
+	if ws := query.Get("w"); ws != "" {
+		width, err := strconv.Atoi(ws)
+		if err != nil {
+			respondError(w, imageproxy.ErrInvalidWidth.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Width = width
+	}
+
+	img, err := imageProxy.Fetch(r.Context(), query.Get("url"), opts)
+	if err != nil {
+		if errors.Is(err, imageproxy.ErrInvalidURL) ||
+			errors.Is(err, imageproxy.ErrInvalidWidth) ||
+			errors.Is(err, imageproxy.ErrInvalidFormat) {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("proxy: %s: %v", query.Get("url"), err)
+		respondError(w, "failed to fetch image", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Write(img.Data)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dnl-fm/md/packages/api/internal/secrets"
+	"github.com/dnl-fm/md/packages/api/internal/share"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	shareStore    *share.Store
+	shareRenderer *share.Renderer
+	secretPolicy  secrets.Policy
+)
+
+// InitializeShares opens the share store in dir. htmlPolicy decides which
+// HTML shared pages may contain, and scan what CreateShare does with content
+// that looks like it contains credentials.
+func InitializeShares(dir string, htmlPolicy share.Policy, scan secrets.Policy) error {
+	var err error
+	if shareRenderer, err = share.NewRenderer(htmlPolicy); err != nil {
+		return fmt.Errorf("invalid share HTML policy: %w", err)
+	}
+	secretPolicy = scan
+	shareStore, err = share.NewStore(dir)
+	if err != nil {
+		return fmt.Errorf("failed to initialize share store: %w", err)
+	}
+	return nil
+}
+
+type CreateShareRequest struct {
+	Content string `json:"content"`
+	TTL     string `json:"ttl"` // Go duration, e.g. "24h"
+}
+
+type CreateShareResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	RawURL      string    `json:"raw_url"`
+	DeleteToken string    `json:"delete_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// SecretWarnings lists likely credentials in the content, so the client
+	// can tell the user to delete the share.
+	SecretWarnings []secrets.Finding `json:"secret_warnings,omitempty"`
+}
+
+func CreateShare(w http.ResponseWriter, r *http.Request) {
+	var req CreateShareRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Content == "" {
+		respondError(w, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			respondError(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var findings []secrets.Finding
+	if secretPolicy != secrets.PolicyOff {
+		findings = secrets.Scan(req.Content)
+	}
+	if len(findings) > 0 && secretPolicy == secrets.PolicyBlock {
+		respondError(w, "content looks like it contains credentials: "+describeFindings(findings), http.StatusUnprocessableEntity)
+		return
+	}
+
+	sh, token, err := shareStore.Create(req.Content, ttl)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	primeCache(sh.Content)
+
+	url := baseURL(r) + "/share/" + sh.ID
+	w.Header().Set("Location", url)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	respondJSON(w, CreateShareResponse{
+		ID:          sh.ID,
+		URL:         url,
+		RawURL:      url + "/raw",
+		DeleteToken: token,
+		ExpiresAt:   sh.ExpiresAt,
+
+		SecretWarnings: findings,
+	})
+}
+
+func describeFindings(findings []secrets.Finding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%s on line %d", f.Rule, f.Line)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func GetShare(w http.ResponseWriter, r *http.Request) {
+	sh, ok := loadShare(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", shareRenderer.ContentSecurityPolicy())
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := shareRenderer.RenderPage(w, sh); err != nil {
+		log.Printf("share %s: render failed: %v", sh.ID, err)
+	}
+}
+
+func GetShareRaw(w http.ResponseWriter, r *http.Request) {
+	sh, ok := loadShare(w, r)
+	if !ok {
+		return
+	}
+
+	// Shares never change, so the id doubles as a strong ETag for If-Range.
+	// ServeContent handles Range requests for clients that load huge
+	// documents lazily.
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("ETag", `"`+sh.ID+`"`)
+	http.ServeContent(w, r, "", sh.CreatedAt, strings.NewReader(sh.Content))
+}
+
+func DeleteShare(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Delete-Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	err := shareStore.Delete(chi.URLParam(r, "id"), token)
+	switch {
+	case errors.Is(err, share.ErrNotFound):
+		respondError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, share.ErrInvalidToken):
+		respondError(w, err.Error(), http.StatusForbidden)
+	case err != nil:
+		respondError(w, "delete failed", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func loadShare(w http.ResponseWriter, r *http.Request) (*share.Share, bool) {
+	sh, err := shareStore.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, share.ErrNotFound) {
+			respondError(w, err.Error(), http.StatusNotFound)
+		} else {
+			respondError(w, "failed to load share", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return sh, true
+}
+
+// publicURL is the configured base for share links, if any.
+var publicURL string
+
+func InitializePublicURL(u string) {
+	publicURL = strings.TrimSuffix(u, "/")
+}
+
+// baseURL is publicURL, or else derived from the request. X-Forwarded-Proto
+// only reaches handlers from trusted proxies (see middleware.RealIP).
+func baseURL(r *http.Request) string {
+	if publicURL != "" {
+		return publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func StartShareJanitor(interval time.Duration, stop <-chan struct{}) {
+	shareStore.StartJanitor(interval, stop)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dnl-fm/md/packages/api/internal/spell"
+)
+
+const defaultLanguage = "en_US"
+
+var dictionaries *spell.Registry
+
+func InitializeDictionaries(dir string) {
+	dictionaries = spell.NewRegistry(dir)
+}
+
+type SpellcheckRequest struct {
+	Content  string   `json:"content"`
+	Language string   `json:"language"`
+	Words    []string `json:"words"`
+}
+
+type SpellcheckResponse struct {
+	Language     string              `json:"language"`
+	Misspellings []spell.Misspelling `json:"misspellings"`
+}
+
+func CheckSpelling(w http.ResponseWriter, r *http.Request) {
+	var req SpellcheckRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Language == "" {
+		req.Language = defaultLanguage
+	}
+
+	dict, err := dictionaries.Get(req.Language)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, SpellcheckResponse{
+		Language:     strings.ReplaceAll(req.Language, "-", "_"),
+		Misspellings: spell.Check(dict, req.Content, req.Words),
+	})
+}
+
+func SpellingLanguages(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, map[string][]string{"languages": dictionaries.Languages()})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dnl-fm/md/packages/api/internal/tabular"
+)
+
+type TableRequest struct {
+	Content   string `json:"content"`
+	Delimiter string `json:"delimiter,omitempty"` // ",", ";" or "\t"; detected when empty
+	Format    string `json:"format,omitempty"`    // "markdown" (default) or "html"
+}
+
+type TableResponse struct {
+	Content string `json:"content"`
+}
+
+// RenderTable converts a CSV/TSV block to a markdown or HTML table.
+func RenderTable(w http.ResponseWriter, r *http.Request) {
+	var req TableRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tbl, err := tabular.Parse(req.Content, req.Delimiter)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Format {
+	case "", "markdown":
+		respondJSON(w, TableResponse{Content: tbl.Markdown()})
+	case "html":
+		respondJSON(w, TableResponse{Content: tbl.HTML()})
+	default:
+		respondError(w, "invalid format, must be 'markdown' or 'html'", http.StatusBadRequest)
+	}
+}
+
+type ChartRequest struct {
+	Content   string `json:"content"`
+	Delimiter string `json:"delimiter,omitempty"`
+	tabular.ChartOptions
+}
+
+// RenderChart turns CSV/TSV data into an SVG bar, line or pie chart.
+func RenderChart(w http.ResponseWriter, r *http.Request) {
+	var req ChartRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tbl, err := tabular.Parse(req.Content, req.Delimiter)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	svg, err := tbl.Chart(req.ChartOptions)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(svg))
+}
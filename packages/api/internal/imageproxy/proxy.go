@@ -0,0 +1,251 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/dnl-fm/md/packages/api/internal/cache"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxWidth       = 4096
+	maxSourceBytes = 10 << 20
+	// maxPixels bounds a decoded source to about 64 MB of RGBA.
+	maxPixels = 16_000_000
+	// maxConcurrent bounds how many images are decoded and re-encoded at
+	// once, since each may take maxPixels of memory twice over.
+	maxConcurrent = 4
+	maxCacheBytes = 64 << 20
+)
+
+var (
+	ErrInvalidURL    = errors.New("invalid url")
+	ErrInvalidFormat = errors.New("invalid format, must be 'webp', 'png' or 'jpeg'")
+	ErrInvalidWidth  = fmt.Errorf("invalid width, must be between 1 and %d", MaxWidth)
+	errBlockedAddr   = errors.New("destination address not allowed")
+)
+
+type Options struct {
+	Width  int
+	Format string
+}
+
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// Proxy fetches remote images, re-encodes them (which drops EXIF and other
+// metadata) and caches the result in memory.
+type Proxy struct {
+	client *http.Client
+	cache  *cache.LRU[Image]
+	sem    chan struct{}
+}
+
+func New() *Proxy {
+	return newProxy(false)
+}
+
+func newProxy(allowPrivate bool) *Proxy {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = rejectPrivate
+	}
+
+	return &Proxy{
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+		},
+		cache: cache.NewSized(512, maxCacheBytes, 24*time.Hour, func(img Image) int { return len(img.Data) }),
+		sem:   make(chan struct{}, maxConcurrent),
+	}
+}
+
+func (p *Proxy) Fetch(ctx context.Context, rawURL string, opts Options) (Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Image{}, ErrInvalidURL
+	}
+	if opts.Width < 0 || opts.Width > MaxWidth {
+		return Image{}, ErrInvalidWidth
+	}
+	switch opts.Format {
+	case "", "webp", "png", "jpeg":
+	default:
+		return Image{}, ErrInvalidFormat
+	}
+
+	key := u.String() + "|" + strconv.Itoa(opts.Width) + "|" + opts.Format
+	if img, ok := p.cache.Get(key); ok {
+		return img, nil
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+		defer func() { <-p.sem }()
+	case <-ctx.Done():
+		return Image{}, ctx.Err()
+	}
+
+	src, srcFormat, err := p.download(ctx, u.String())
+	if err != nil {
+		return Image{}, err
+	}
+
+	if opts.Width > 0 && opts.Width < src.Bounds().Dx() {
+		src = resize(src, opts.Width)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = "png"
+		if srcFormat == "jpeg" {
+			format = "jpeg"
+		}
+	}
+
+	img, err := encode(src, format)
+	if err != nil {
+		return Image{}, err
+	}
+
+	p.cache.Set(key, img)
+	return img, nil
+}
+
+func (p *Proxy) download(ctx context.Context, rawURL string) (image.Image, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", ErrInvalidURL
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetch failed: upstream returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch failed: %w", err)
+	}
+	if len(body) > maxSourceBytes {
+		return nil, "", fmt.Errorf("image too large (max %d bytes)", maxSourceBytes)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("image dimensions too large")
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported image: %w", err)
+	}
+	return img, format, nil
+}
+
+func resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image, format string) (Image, error) {
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+
+	switch format {
+	case "webp":
+		contentType = "image/webp"
+		err = nativewebp.Encode(&buf, img, nil)
+	case "jpeg":
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	default:
+		contentType = "image/png"
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("encode failed: %w", err)
+	}
+
+	return Image{Data: buf.Bytes(), ContentType: contentType}, nil
+}
+
+// blockedNets are ranges the net.IP predicates miss: "this network" and
+// carrier-grade NAT, which cloud providers use for internal services.
+var blockedNets = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// rejectPrivate prevents the proxy from being used to reach loopback,
+// link-local, private or carrier-grade NAT addresses (including via
+// redirects).
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if blockedIP(net.ParseIP(host)) {
+		return errBlockedAddr
+	}
+	return nil
+}
+
+func blockedIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
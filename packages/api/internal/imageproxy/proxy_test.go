@@ -0,0 +1,95 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		img.Set(x, 50, color.RGBA{255, 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchResizeAndFormat(t *testing.T) {
+	srv := testServer(t)
+	p := newProxy(true)
+
+	img, err := p.Fetch(context.Background(), srv.URL+"/a.png", Options{Width: 50, Format: "webp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.ContentType != "image/webp" {
+		t.Errorf("expected image/webp, got %s", img.ContentType)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if format != "webp" || cfg.Width != 50 || cfg.Height != 25 {
+		t.Errorf("expected 50x25 webp, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+}
+
+func TestFetchInvalidInput(t *testing.T) {
+	p := newProxy(true)
+
+	cases := []struct {
+		url  string
+		opts Options
+		want error
+	}{
+		{"ftp://example.com/a.png", Options{}, ErrInvalidURL},
+		{"not a url", Options{}, ErrInvalidURL},
+		{"https://example.com/a.png", Options{Width: MaxWidth + 1}, ErrInvalidWidth},
+		{"https://example.com/a.png", Options{Format: "bmp"}, ErrInvalidFormat},
+	}
+
+	for _, c := range cases {
+		if _, err := p.Fetch(context.Background(), c.url, c.opts); err != c.want {
+			t.Errorf("%s %+v: expected %v, got %v", c.url, c.opts, c.want, err)
+		}
+	}
+}
+
+func TestFetchRejectsPrivateAddresses(t *testing.T) {
+	srv := testServer(t)
+	p := New()
+
+	if _, err := p.Fetch(context.Background(), srv.URL+"/a.png", Options{}); err == nil {
+		t.Error("expected loopback fetch to be rejected")
+	}
+
+	for addr, want := range map[string]bool{
+		"100.64.0.1:80":      true,
+		"100.127.255.254:80": true,
+		"0.1.2.3:80":         true,
+		"10.0.0.1:80":        true,
+		"[::1]:80":           true,
+		"100.128.0.1:80":     false,
+		"93.184.216.34:443":  false,
+	} {
+		if err := rejectPrivate("tcp", addr, nil); (err != nil) != want {
+			t.Errorf("%s: expected blocked=%v, got %v", addr, want, err)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type FormatOptions struct {
+	ListMarker string `json:"list_marker,omitempty"` // "-", "*" or "+" (default "-")
+	WrapWidth  int    `json:"wrap_width,omitempty"`  // 0 disables paragraph wrapping
+}
+
+var (
+	bulletRe    = regexp.MustCompile(`^(\s*)[-*+](\s+)(.*)$`)
+	orderedRe   = regexp.MustCompile(`^\s*\d+[.)]\s`)
+	setextRe    = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	thematicRe  = regexp.MustCompile(`^ {0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	delimCellRe = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+	// blockStartRe matches words that would open a block (list item,
+	// heading, quote, fence, HTML, setext underline or thematic break) at
+	// the start of a line.
+	blockStartRe = regexp.MustCompile("^(?:[-*+]|#{1,6}|\\d{1,9}[.)]|=+|-+|_{3,}|\\*{3,}|[>|<].*|```.*|~~~.*)$")
+)
+
+// Format normalizes a markdown document: ATX headings surrounded by blank
+// lines, a single bullet marker, aligned tables, collapsed blank lines and
+// optional paragraph wrapping. Fenced code and front matter are left as-is.
+func Format(content string, opts FormatOptions) (string, error) {
+	switch opts.ListMarker {
+	case "":
+		opts.ListMarker = "-"
+	case "-", "*", "+":
+	default:
+		return "", fmt.Errorf("invalid list_marker, must be '-', '*' or '+'")
+	}
+	if opts.WrapWidth < 0 {
+		return "", fmt.Errorf("invalid wrap_width")
+	}
+
+	lines := SplitLines(content)
+	var out []string
+	var para []string
+	inCode := false
+	inList := false // indented lines continue a list rather than start code
+
+	flushPara := func() {
+		if len(para) == 0 {
+			return
+		}
+		// A hard break at the end of a paragraph has no effect.
+		para[len(para)-1] = strings.TrimRight(para[len(para)-1], " ")
+		if opts.WrapWidth > 0 {
+			out = append(out, wrapParagraph(para, opts.WrapWidth)...)
+		} else {
+			out = append(out, para...)
+		}
+		para = nil
+	}
+	blank := func() {
+		flushPara()
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		if l.FrontMatter || l.InFence {
+			out = append(out, l.Text)
+			continue
+		}
+		if l.Fence {
+			if !inCode {
+				blank()
+			}
+			out = append(out, l.Text)
+			if inCode {
+				blank()
+			}
+			inCode = !inCode
+			continue
+		}
+
+		text := strings.TrimRight(l.Text, " \t")
+		if strings.HasSuffix(l.Text, "  ") && text != "" {
+			text += "  "
+		}
+
+		if text == "" {
+			blank()
+			continue
+		}
+
+		indented := strings.HasPrefix(text, "    ") || strings.HasPrefix(text, "\t")
+		lazy := len(para) == 0 && len(out) > 0 && out[len(out)-1] != "" && isListLine(out[len(out)-1])
+		if !indented && !lazy && !isListLine(text) {
+			inList = false
+		}
+
+		// Indented code is checked before bullets so its content isn't rewritten.
+		if indented && !inList && len(para) == 0 {
+			out = append(out, text)
+			continue
+		}
+
+		if level, title := Heading(l); level > 0 {
+			blank()
+			out = append(out, strings.Repeat("#", level)+" "+strings.TrimSpace(title))
+			blank()
+			continue
+		}
+
+		// Setext heading: paragraph line followed by === or ---.
+		if len(para) > 0 && setextRe.MatchString(text) {
+			level := 1
+			if strings.Contains(text, "-") {
+				level = 2
+			}
+			title := strings.Join(strings.Fields(strings.Join(para, " ")), " ")
+			para = nil
+			blank()
+			out = append(out, strings.Repeat("#", level)+" "+title)
+			blank()
+			continue
+		}
+
+		if isTableStart(lines, i) {
+			blank()
+			j := i
+			for j < len(lines) && strings.Contains(lines[j].Text, "|") && !isBlank(lines[j]) {
+				j++
+			}
+			out = append(out, alignTable(lines[i:j])...)
+			i = j - 1
+			blank()
+			continue
+		}
+
+		if thematicRe.MatchString(text) {
+			blank()
+			out = append(out, "---")
+			blank()
+			continue
+		}
+
+		if m := bulletRe.FindStringSubmatch(text); m != nil && m[3] != "" {
+			if len(para) > 0 {
+				blank()
+			}
+			out = append(out, m[1]+opts.ListMarker+" "+m[3])
+			inList = true
+			continue
+		}
+
+		if orderedRe.MatchString(text) || strings.HasPrefix(strings.TrimSpace(text), ">") ||
+			strings.HasPrefix(text, "<") || indented {
+			flushPara()
+			out = append(out, text)
+			inList = inList || orderedRe.MatchString(text)
+			continue
+		}
+
+		// Lazy continuation of a list item stays with the item.
+		if lazy {
+			out = append(out, text)
+			continue
+		}
+
+		para = append(para, text)
+	}
+	flushPara()
+
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+func isListLine(s string) bool {
+	return bulletRe.MatchString(s) || orderedRe.MatchString(s)
+}
+
+func isTableStart(lines []Line, i int) bool {
+	// Both rows need a pipe: without one in the delimiter row, "a | b\n---"
+	// is a setext heading rather than a table.
+	if i+1 >= len(lines) || !strings.Contains(lines[i].Text, "|") || !strings.Contains(lines[i+1].Text, "|") {
+		return false
+	}
+	cells := splitRow(lines[i+1].Text)
+	if len(cells) == 0 {
+		return false
+	}
+	for _, c := range cells {
+		if !delimCellRe.MatchString(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(row); i++ {
+		if row[i] == '\\' && i+1 < len(row) && row[i+1] == '|' {
+			cur.WriteString(`\|`)
+			i++
+			continue
+		}
+		if row[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(row[i])
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+func alignTable(rows []Line) []string {
+	cells := make([][]string, len(rows))
+	cols := 0
+	for i, r := range rows {
+		cells[i] = splitRow(r.Text)
+		if len(cells[i]) > cols {
+			cols = len(cells[i])
+		}
+	}
+
+	aligns := make([]string, cols)
+	for c, d := range cells[1] {
+		left, right := strings.HasPrefix(d, ":"), strings.HasSuffix(d, ":")
+		switch {
+		case left && right:
+			aligns[c] = "center"
+		case right:
+			aligns[c] = "right"
+		case left:
+			aligns[c] = "left"
+		}
+	}
+
+	widths := make([]int, cols)
+	for i, row := range cells {
+		if i == 1 {
+			continue
+		}
+		for c, cell := range row {
+			widths[c] = max(widths[c], utf8.RuneCountInString(cell))
+		}
+	}
+	for c := range widths {
+		widths[c] = max(widths[c], 3)
+	}
+
+	out := make([]string, len(cells))
+	for i, row := range cells {
+		parts := make([]string, cols)
+		for c := 0; c < cols; c++ {
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			if i == 1 {
+				parts[c] = delimiter(aligns[c], widths[c])
+				continue
+			}
+			pad := widths[c] - utf8.RuneCountInString(cell)
+			switch aligns[c] {
+			case "right":
+				parts[c] = strings.Repeat(" ", pad) + cell
+			case "center":
+				parts[c] = strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+			default:
+				parts[c] = cell + strings.Repeat(" ", pad)
+			}
+		}
+		out[i] = "| " + strings.Join(parts, " | ") + " |"
+	}
+	return out
+}
+
+func delimiter(align string, width int) string {
+	switch align {
+	case "center":
+		return ":" + strings.Repeat("-", width-2) + ":"
+	case "right":
+		return strings.Repeat("-", width-1) + ":"
+	case "left":
+		return ":" + strings.Repeat("-", width-1)
+	default:
+		return strings.Repeat("-", width)
+	}
+}
+
+// wrapParagraph re-flows paragraph text to width, keeping hard line breaks.
+// Lines never break before a word that would turn the next line into a
+// block, so wrapped output formats the same way again.
+func wrapParagraph(para []string, width int) []string {
+	var out []string
+	var words []string
+
+	flush := func(hardBreak bool) {
+		line := ""
+		for _, w := range words {
+			switch {
+			case line == "":
+				line = w
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w) > width && !blockStartRe.MatchString(w):
+				out = append(out, line)
+				line = w
+			default:
+				line += " " + w
+			}
+		}
+		if hardBreak {
+			line += "  "
+		}
+		out = append(out, line)
+		words = nil
+	}
+
+	for i, p := range para {
+		words = append(words, strings.Fields(p)...)
+		if strings.HasSuffix(p, "  ") && i < len(para)-1 {
+			flush(true)
+		}
+	}
+	if len(words) > 0 {
+		flush(false)
+	}
+	return out
+}
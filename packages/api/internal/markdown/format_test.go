@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	input := "Title\n=====\nSome text   \n* one\n+ two\n\n\n\n|a|bb|\n|:-|-:|\n|longer cell|1|\n```go\n*  untouched\n```\n#   Heading ##\n***"
+	expected := "# Title\n\nSome text\n\n- one\n- two\n\n| a           |  bb |\n| :---------- | --: |\n| longer cell |   1 |\n\n```go\n*  untouched\n```\n\n# Heading\n\n---\n"
+
+	got, err := Format(input, FormatOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	again, _ := Format(got, FormatOptions{})
+	if again != got {
+		t.Errorf("format is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatWrapAndMarker(t *testing.T) {
+	input := "one two three four five six\n\n- item\n"
+	expected := "one two three\nfour five six\n\n* item\n"
+
+	got, err := Format(input, FormatOptions{WrapWidth: 14, ListMarker: "*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("unexpected output:\n%q\nexpected:\n%q", got, expected)
+	}
+
+	if _, err := Format(input, FormatOptions{ListMarker: "x"}); err == nil {
+		t.Error("expected error for invalid list marker")
+	}
+}
+
+func TestFormatKeepsIndentedCode(t *testing.T) {
+	input := "Para\n\n    * x\n    + y\n\n- item\n\n    * nested\n"
+	expected := "Para\n\n    * x\n    + y\n\n- item\n\n    - nested\n"
+
+	got, err := Format(input, FormatOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("unexpected output:\n%q\nexpected:\n%q", got, expected)
+	}
+}
+
+func TestFormatWrapAvoidsBlockMarkers(t *testing.T) {
+	for _, input := range []string{
+		"aaaa bbbb - cccc\n",
+		"aaaa bbbb 1. cccc\n",
+		"aaaa bbbb # cccc\n",
+		"aaaa bbbb > cccc\n",
+		"aaaa bbbb --- cccc\n",
+	} {
+		got, err := Format(input, FormatOptions{WrapWidth: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lines := SplitLines(got); len(lines) < 2 || blockStartRe.MatchString(strings.Fields(lines[1].Text)[0]) {
+			t.Errorf("%q: wrapped line starts a block: %q", input, got)
+		}
+		if again, _ := Format(got, FormatOptions{WrapWidth: 10}); again != got {
+			t.Errorf("%q: format is not idempotent: %q then %q", input, got, again)
+		}
+	}
+}
+
+func TestFormatPipeWithoutTable(t *testing.T) {
+	for input, expected := range map[string]string{
+		"a | b\n---\n":         "## a | b\n",
+		"foo|bar\n:-:\ntext\n": "foo|bar\n:-:\ntext\n",
+	} {
+		got, err := Format(input, FormatOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != expected {
+			t.Errorf("%q: unexpected output %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Line is a single source line annotated with the block context it appears in.
+type Line struct {
+	Number      int // 1-based
+	Text        string
+	InFence     bool // inside a fenced code block (excluding the fence lines)
+	Fence       bool // opening or closing fence line
+	FrontMatter bool // inside or delimiting a leading YAML front matter block
+}
+
+var (
+	fenceRe   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+)
+
+// SplitLines splits content into lines and tracks fenced code blocks and
+// front matter so rules can skip non-prose content.
+func SplitLines(content string) []Line {
+	raw := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(raw) > 0 && raw[len(raw)-1] == "" {
+		raw = raw[:len(raw)-1]
+	}
+
+	lines := make([]Line, len(raw))
+	var fence string
+	inFrontMatter := len(raw) > 0 && raw[0] == "---"
+
+	for i, text := range raw {
+		l := Line{Number: i + 1, Text: text}
+
+		switch {
+		case inFrontMatter:
+			l.FrontMatter = true
+			if i > 0 && (text == "---" || text == "...") {
+				inFrontMatter = false
+			}
+		case fence != "":
+			if m := fenceRe.FindStringSubmatch(text); m != nil && m[1][0] == fence[0] &&
+				len(m[1]) >= len(fence) && strings.TrimSpace(m[2]) == "" {
+				l.Fence = true
+				fence = ""
+			} else {
+				l.InFence = true
+			}
+		default:
+			if m := fenceRe.FindStringSubmatch(text); m != nil {
+				l.Fence = true
+				fence = m[1]
+			}
+		}
+
+		lines[i] = l
+	}
+
+	return lines
+}
+
+// Heading returns the ATX heading level and text of a line, or 0 if the line
+// is not a heading.
+func Heading(l Line) (int, string) {
+	if l.InFence || l.Fence || l.FrontMatter {
+		return 0, ""
+	}
+	m := headingRe.FindStringSubmatch(l.Text)
+	if m == nil {
+		return 0, ""
+	}
+	return len(m[1]), m[2]
+}
+
+// FenceInfo returns the info string (language) of an opening fence line.
+func FenceInfo(l Line) string {
+	m := fenceRe.FindStringSubmatch(l.Text)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[2])
+}
+
+// CodeBlock is a fenced code block.
+type CodeBlock struct {
+	Lang string // first word of the info string
+	Code string
+	Line int // line of the opening fence
+}
+
+// CodeBlocks returns the fenced code blocks in content. A block left open
+// at the end of the document is dropped.
+func CodeBlocks(content string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var body []string
+
+	for _, l := range SplitLines(content) {
+		switch {
+		case l.Fence && current == nil:
+			lang, _, _ := strings.Cut(FenceInfo(l), " ")
+			current = &CodeBlock{Lang: lang, Line: l.Number}
+			body = nil
+		case l.Fence:
+			current.Code = strings.Join(body, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+		case l.InFence:
+			body = append(body, l.Text)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,254 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	DefaultLineLength = 80
+)
+
+type Diagnostic struct {
+	Rule     string `json:"rule"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// LintConfig selects which rules run. Rules maps a rule ID (e.g. "MD013") to
+// enabled/disabled; rules that are not listed use their default.
+type LintConfig struct {
+	Rules      map[string]bool `json:"rules,omitempty"`
+	LineLength int             `json:"line_length,omitempty"`
+}
+
+type rule struct {
+	id       string
+	severity string
+	enabled  bool
+	check    func(lines []Line, content string, cfg LintConfig) []Diagnostic
+}
+
+var (
+	missingSpaceRe = regexp.MustCompile(`^ {0,3}#{1,6}[^#\s]`)
+	tableRowRe     = regexp.MustCompile(`^\s*\|`)
+)
+
+var rules = []rule{
+	{"MD001", SeverityError, true, checkHeadingIncrement},
+	{"MD009", SeverityWarning, true, checkTrailingSpaces},
+	{"MD010", SeverityWarning, true, checkHardTabs},
+	{"MD012", SeverityWarning, true, checkMultipleBlanks},
+	{"MD013", SeverityWarning, true, checkLineLength},
+	{"MD018", SeverityError, true, checkMissingHeadingSpace},
+	{"MD022", SeverityWarning, true, checkBlanksAroundHeadings},
+	{"MD025", SeverityError, true, checkSingleH1},
+	{"MD031", SeverityWarning, true, checkBlanksAroundFences},
+	{"MD040", SeverityWarning, true, checkFenceLanguage},
+	{"MD047", SeverityWarning, true, checkTrailingNewline},
+}
+
+// Lint runs the enabled rules against content and returns diagnostics ordered
+// by line number.
+func Lint(content string, cfg LintConfig) ([]Diagnostic, error) {
+	for id := range cfg.Rules {
+		if !knownRule(id) {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+	if cfg.LineLength <= 0 {
+		cfg.LineLength = DefaultLineLength
+	}
+
+	lines := SplitLines(content)
+	diagnostics := []Diagnostic{}
+	for _, r := range rules {
+		enabled := r.enabled
+		if v, ok := cfg.Rules[r.id]; ok {
+			enabled = v
+		}
+		if !enabled {
+			continue
+		}
+		for _, d := range r.check(lines, content, cfg) {
+			d.Rule = r.id
+			d.Severity = r.severity
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics, nil
+}
+
+func knownRule(id string) bool {
+	for _, r := range rules {
+		if r.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(l Line) bool {
+	return strings.TrimSpace(l.Text) == ""
+}
+
+func checkHeadingIncrement(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	prev := 0
+	for _, l := range lines {
+		level, _ := Heading(l)
+		if level == 0 {
+			continue
+		}
+		if prev > 0 && level > prev+1 {
+			out = append(out, Diagnostic{Line: l.Number, Message: fmt.Sprintf("heading levels should only increment by one level at a time (expected h%d, got h%d)", prev+1, level)})
+		}
+		prev = level
+	}
+	return out
+}
+
+func checkTrailingSpaces(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, l := range lines {
+		if l.InFence || isBlank(l) {
+			continue
+		}
+		trimmed := strings.TrimRight(l.Text, " \t")
+		trailing := len(l.Text) - len(trimmed)
+		// Exactly two trailing spaces is a hard line break.
+		if trailing > 0 && l.Text[len(trimmed):] != "  " {
+			out = append(out, Diagnostic{Line: l.Number, Message: "trailing spaces"})
+		}
+	}
+	return out
+}
+
+func checkHardTabs(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, l := range lines {
+		if !l.InFence && strings.Contains(l.Text, "\t") {
+			out = append(out, Diagnostic{Line: l.Number, Message: "hard tabs"})
+		}
+	}
+	return out
+}
+
+func checkMultipleBlanks(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for i := 1; i < len(lines); i++ {
+		if !lines[i].InFence && isBlank(lines[i]) && isBlank(lines[i-1]) {
+			out = append(out, Diagnostic{Line: lines[i].Number, Message: "multiple consecutive blank lines"})
+		}
+	}
+	return out
+}
+
+func checkLineLength(lines []Line, _ string, cfg LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, l := range lines {
+		if l.InFence || l.Fence || l.FrontMatter || tableRowRe.MatchString(l.Text) {
+			continue
+		}
+		n := len([]rune(l.Text))
+		// Long lines without whitespace past the limit are usually URLs.
+		if n > cfg.LineLength && strings.ContainsAny(string([]rune(l.Text)[cfg.LineLength:]), " \t") {
+			out = append(out, Diagnostic{Line: l.Number, Message: fmt.Sprintf("line length %d exceeds %d", n, cfg.LineLength)})
+		}
+	}
+	return out
+}
+
+func checkMissingHeadingSpace(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, l := range lines {
+		if l.InFence || l.Fence || l.FrontMatter {
+			continue
+		}
+		if missingSpaceRe.MatchString(l.Text) {
+			out = append(out, Diagnostic{Line: l.Number, Message: "no space after hash on atx style heading"})
+		}
+	}
+	return out
+}
+
+func checkBlanksAroundHeadings(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	for i, l := range lines {
+		if level, _ := Heading(l); level == 0 {
+			continue
+		}
+		if i > 0 && !isBlank(lines[i-1]) && !lines[i-1].FrontMatter {
+			out = append(out, Diagnostic{Line: l.Number, Message: "headings should be preceded by a blank line"})
+		}
+		if i < len(lines)-1 && !isBlank(lines[i+1]) {
+			out = append(out, Diagnostic{Line: l.Number, Message: "headings should be followed by a blank line"})
+		}
+	}
+	return out
+}
+
+func checkSingleH1(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	seen := false
+	for _, l := range lines {
+		if level, _ := Heading(l); level != 1 {
+			continue
+		}
+		if seen {
+			out = append(out, Diagnostic{Line: l.Number, Message: "multiple top-level headings in the same document"})
+		}
+		seen = true
+	}
+	return out
+}
+
+func checkBlanksAroundFences(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	open := false
+	for i, l := range lines {
+		if !l.Fence {
+			continue
+		}
+		open = !open
+		if open && i > 0 && !isBlank(lines[i-1]) && !lines[i-1].FrontMatter {
+			out = append(out, Diagnostic{Line: l.Number, Message: "fenced code blocks should be preceded by a blank line"})
+		}
+		if !open && i < len(lines)-1 && !isBlank(lines[i+1]) {
+			out = append(out, Diagnostic{Line: l.Number, Message: "fenced code blocks should be followed by a blank line"})
+		}
+	}
+	return out
+}
+
+func checkFenceLanguage(lines []Line, _ string, _ LintConfig) []Diagnostic {
+	var out []Diagnostic
+	open := false
+	for _, l := range lines {
+		if !l.Fence {
+			continue
+		}
+		open = !open
+		if open && FenceInfo(l) == "" {
+			out = append(out, Diagnostic{Line: l.Number, Message: "fenced code blocks should have a language specified"})
+		}
+	}
+	return out
+}
+
+func checkTrailingNewline(lines []Line, content string, _ LintConfig) []Diagnostic {
+	if content == "" || strings.HasSuffix(content, "\n") {
+		return nil
+	}
+	return []Diagnostic{{Line: len(lines), Message: "files should end with a single newline character"}}
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import "testing"
+
+func rulesHit(diagnostics []Diagnostic) map[string]int {
+	hit := map[string]int{}
+	for _, d := range diagnostics {
+		hit[d.Rule] = d.Line
+	}
+	return hit
+}
+
+func TestLintDetectsCommonIssues(t *testing.T) {
+	content := "# Title\n\n### Skipped\n\n#NoSpace\n\ntext \n\n\n```\ncode\t\n```\n\n# Second"
+
+	diagnostics, err := Lint(content, LintConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hit := rulesHit(diagnostics)
+	expected := map[string]int{
+		"MD001": 3,
+		"MD018": 5,
+		"MD009": 7,
+		"MD012": 9,
+		"MD040": 10,
+		"MD025": 14,
+		"MD047": 14,
+	}
+	for rule, line := range expected {
+		if hit[rule] != line {
+			t.Errorf("expected %s on line %d, got %d", rule, line, hit[rule])
+		}
+	}
+	if _, ok := hit["MD010"]; ok {
+		t.Error("hard tabs inside code fences should be ignored")
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	content := "# Title\n\nThis line is definitely longer than twenty characters.\n"
+
+	diagnostics, err := Lint(content, LintConfig{LineLength: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := rulesHit(diagnostics)["MD013"]; !ok {
+		t.Error("expected MD013 with line_length 20")
+	}
+
+	diagnostics, _ = Lint(content, LintConfig{LineLength: 20, Rules: map[string]bool{"MD013": false}})
+	if len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics with MD013 disabled, got %v", diagnostics)
+	}
+
+	if _, err := Lint(content, LintConfig{Rules: map[string]bool{"MD999": true}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestSplitLinesFrontMatterAndFences(t *testing.T) {
+	lines := SplitLines("---\ntitle: x\n---\n~~~go\n# not a heading\n~~~\n# Heading\n")
+
+	if !lines[1].FrontMatter || lines[3].InFence || !lines[3].Fence || !lines[4].InFence {
+		t.Errorf("unexpected line classification: %+v", lines)
+	}
+	if level, _ := Heading(lines[4]); level != 0 {
+		t.Error("heading inside fence should be ignored")
+	}
+	if level, text := Heading(lines[6]); level != 1 || text != "Heading" {
+		t.Errorf("expected h1 'Heading', got h%d %q", level, text)
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	blocks := CodeBlocks("# Doc\n\n```mermaid\ngraph TD\n  A-->B\n```\n\n~~~ go title\nfmt.Println()\n~~~\n\n```js\nunclosed\n")
+
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 closed blocks, got %+v", blocks)
+	}
+	if blocks[0].Lang != "mermaid" || blocks[0].Code != "graph TD\n  A-->B" || blocks[0].Line != 3 {
+		t.Errorf("unexpected mermaid block %+v", blocks[0])
+	}
+	if blocks[1].Lang != "go" || blocks[1].Code != "fmt.Println()" {
+		t.Errorf("unexpected go block %+v", blocks[1])
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecompressBody decodes request bodies sent with Content-Encoding gzip or
+// zstd, so clients on slow uplinks can compress large documents. At most
+// limit decoded bytes are read; past that the body fails like an oversized
+// plain one, which keeps compression bombs from inflating in memory. Other
+// encodings are rejected with 415.
+func DecompressBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := decodeBody(encoding, r.Body, limit)
+			if errors.Is(err, errUnsupportedEncoding) {
+				w.Header().Set("Accept-Encoding", "gzip, zstd")
+				respondError(w, err.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			if err != nil {
+				respondError(w, "invalid compressed body", http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			r.Body = http.MaxBytesReader(w, body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// zstdMaxWindow is the largest window RFC 8878 asks decoders to support.
+// Streaming encoders declare it regardless of input size, so it can't be
+// tied to limit; the decoded size is capped separately.
+const zstdMaxWindow = 8 << 20
+
+func decodeBody(encoding string, body io.ReadCloser, limit int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{zr, func() { zr.Close(); body.Close() }}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{zr, func() { zr.Close(); body.Close() }}, nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	close func()
+}
+
+func (rc readCloser) Close() error {
+	rc.close()
+	return nil
+}
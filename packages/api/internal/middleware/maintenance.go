@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Maintenance is a switch that turns away requests with 503 while backups or
+// migrations run. It is on while Set(true) is in effect or while its flag
+// file exists, so cron jobs can toggle it with touch and rm.
+type Maintenance struct {
+	enabled    atomic.Bool
+	file       string
+	blockReads bool
+	retryAfter time.Duration
+}
+
+// NewMaintenance returns a switch that is off. file may be empty. Reads are
+// still served during maintenance unless blockReads is set.
+func NewMaintenance(file string, blockReads bool, retryAfter time.Duration) *Maintenance {
+	if retryAfter <= 0 {
+		retryAfter = 5 * time.Minute
+	}
+	return &Maintenance{file: file, blockReads: blockReads, retryAfter: retryAfter}
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	if m.enabled.Load() {
+		return true
+	}
+	if m.file == "" {
+		return false
+	}
+	_, err := os.Stat(m.file)
+	return err == nil
+}
+
+// Set turns maintenance mode on or off. It can't turn off a mode enabled by
+// the flag file.
+func (m *Maintenance) Set(on bool) {
+	m.enabled.Store(on)
+}
+
+// Guard rejects requests while maintenance mode is on. write marks routes
+// that change stored state; the others are only rejected with blockReads.
+func (m *Maintenance) Guard(write bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !write && !m.blockReads {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.Enabled() {
+				w.Header().Set("Retry-After", strconv.Itoa(int(m.retryAfter.Seconds())))
+				respondError(w, "service is in maintenance, try again later", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Toggle is an admin handler: GET reports the mode, PUT turns it on and
+// DELETE turns it off.
+func (m *Maintenance) Toggle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		m.Set(true)
+	case http.MethodDelete:
+		m.Set(false)
+	case http.MethodGet:
+	default:
+		respondError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"maintenance": m.Enabled()})
+}
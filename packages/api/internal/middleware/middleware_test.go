@@ -0,0 +1,345 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestSignedURL(t *testing.T) {
+	key := []byte("secret")
+	h := SignedURL(key)(okHandler)
+	path := "/render/mermaid/dark/abc"
+	query := url.Values{"code": {"Z3JhcGg"}, "version": {"10.9.1"}}
+
+	valid := time.Now().Add(time.Hour).Unix()
+	expired := time.Now().Add(-time.Hour).Unix()
+	signed := func(q url.Values, sig string, exp int64) string {
+		return fmt.Sprintf("%s&exp=%d&sig=%s", q.Encode(), exp, sig)
+	}
+
+	cases := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"valid", signed(query, Sign(key, path, query, valid), valid), http.StatusOK},
+		{"with token", signed(query, Sign(key, path, query, valid), valid) + "&token=t", http.StatusOK},
+		{"missing", "", http.StatusForbidden},
+		{"expired", signed(query, Sign(key, path, query, expired), expired), http.StatusForbidden},
+		{"wrong path", signed(query, Sign(key, "/other", query, valid), valid), http.StatusForbidden},
+		{"wrong key", signed(query, Sign([]byte("x"), path, query, valid), valid), http.StatusForbidden},
+		{"changed query", signed(url.Values{"code": {"Z3JhcGg"}, "version": {"11.0.0"}}, Sign(key, path, query, valid), valid), http.StatusForbidden},
+		{"added query", signed(query, Sign(key, path, query, valid), valid) + "&font=inter", http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?"+c.query, nil))
+		if w.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, w.Code)
+		}
+	}
+}
+
+func TestSignedURLDisabled(t *testing.T) {
+	w := httptest.NewRecorder()
+	SignedURL(nil)(okHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/render/ascii/abc", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 without key, got %d", w.Code)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	h := RequireToken([]string{"t1", "t2"})(okHandler)
+
+	cases := []struct {
+		name   string
+		header string
+		query  string
+		want   int
+	}{
+		{"bearer", "Bearer t2", "", http.StatusOK},
+		{"query", "", "token=t1", http.StatusOK},
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong", "Bearer nope", "", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/render/ascii/abc?"+c.query, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, w.Code)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	trusted, err := ParseNetworks([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := RequestID(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, "boom", http.StatusInternalServerError)
+	}))
+
+	cases := []struct {
+		name    string
+		remote  string
+		inbound string
+		keep    bool
+	}{
+		{"trusted proxy", "10.1.2.3:1234", "abc-123", true},
+		{"trusted bare ip", "127.0.0.1:1234", "abc-123", true},
+		{"untrusted", "203.0.113.5:1234", "abc-123", false},
+		{"invalid id", "10.1.2.3:1234", "bad id\n", false},
+		{"none", "10.1.2.3:1234", "", false},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/render/ascii/abc", nil)
+		req.RemoteAddr = c.remote
+		if c.inbound != "" {
+			req.Header.Set(RequestIDHeader, c.inbound)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if id == "" {
+			t.Errorf("%s: missing response header", c.name)
+			continue
+		}
+		if (id == c.inbound) != c.keep {
+			t.Errorf("%s: got id %q for inbound %q", c.name, id, c.inbound)
+		}
+		var body errorResponse
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.RequestID != id {
+			t.Errorf("%s: error body request_id %q, header %q", c.name, body.RequestID, id)
+		}
+	}
+}
+
+func TestParseNetworksInvalid(t *testing.T) {
+	if _, err := ParseNetworks([]string{"not-an-ip"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	flag := t.TempDir() + "/maintenance"
+	m := NewMaintenance(flag, false, time.Minute)
+	write := m.Guard(true)(okHandler)
+	read := m.Guard(false)(okHandler)
+
+	serve := func(h http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/share", nil))
+		return w
+	}
+
+	if w := serve(write); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 while off, got %d", w.Code)
+	}
+
+	m.Set(true)
+	w := serve(write)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected 503 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := serve(read); w.Code != http.StatusOK {
+		t.Errorf("expected reads to be served, got %d", w.Code)
+	}
+	m.Set(false)
+
+	if err := os.WriteFile(flag, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(write); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected flag file to enable maintenance, got %d", w.Code)
+	}
+
+	strict := NewMaintenance("", true, 0)
+	strict.Set(true)
+	if w := serve(strict.Guard(false)(okHandler)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected reads to be blocked, got %d", w.Code)
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	allow, _ := ParseNetworks([]string{"10.0.0.0/8"})
+	deny, _ := ParseNetworks([]string{"10.6.6.6"})
+	h := IPFilter("admin", allow, deny)(okHandler)
+
+	cases := []struct {
+		remote string
+		want   int
+	}{
+		{"10.1.2.3:1234", http.StatusOK},
+		{"10.6.6.6:1234", http.StatusForbidden},
+		{"203.0.113.5:1234", http.StatusForbidden},
+		{"@", http.StatusOK}, // unix socket peer
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil)
+		req.RemoteAddr = c.remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.remote, c.want, w.Code)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, _ := ParseNetworks([]string{"10.0.0.0/8"})
+	var got string
+	h := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted peer", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.5:1234"},
+		{"client real ip", "10.0.0.1:1234", map[string]string{"X-Real-IP": "1.2.3.4", "X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed prefix", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"garbage", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "nope"}, "10.0.0.1:1234"},
+		{"no headers", "10.0.0.1:1234", nil, "10.0.0.1:1234"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remote
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got != c.want {
+			t.Errorf("%s: expected %q, got %q", c.name, c.want, got)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if req.Header.Get("X-Forwarded-Proto") != "" {
+		t.Error("expected X-Forwarded-Proto from an untrusted peer to be dropped")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	q := NewQuota(2, map[string]int{"big": 3, "free": 0})
+	now := time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	h := q.Handler(okHandler)
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/render/ascii/abc", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := serve("t1")
+		if w.Code != want {
+			t.Errorf("request %d: expected %d, got %d", i, want, w.Code)
+		}
+		if i == 1 && w.Header().Get("X-Quota-Remaining") != "0" {
+			t.Errorf("expected 0 remaining, got %q", w.Header().Get("X-Quota-Remaining"))
+		}
+		if i == 2 && w.Header().Get("Retry-After") != "3601" {
+			t.Errorf("expected Retry-After until midnight, got %q", w.Header().Get("Retry-After"))
+		}
+	}
+
+	if w := serve("big"); w.Code != http.StatusOK || w.Header().Get("X-Quota-Limit") != "3" {
+		t.Errorf("expected override limit, got %d %q", w.Code, w.Header().Get("X-Quota-Limit"))
+	}
+	for i := 0; i < 5; i++ {
+		if w := serve("free"); w.Code != http.StatusOK || w.Header().Get("X-Quota-Limit") != "" {
+			t.Fatalf("expected unlimited token, got %d", w.Code)
+		}
+	}
+	if w := serve(""); w.Code != http.StatusOK {
+		t.Errorf("expected requests without a token to pass, got %d", w.Code)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if w := serve("t1"); w.Code != http.StatusOK {
+		t.Errorf("expected quota to reset the next day, got %d", w.Code)
+	}
+	if len(q.used) != 1 {
+		t.Errorf("expected earlier days to be dropped, got %v", q.used)
+	}
+}
+
+func TestDecompressBody(t *testing.T) {
+	echo := DecompressBody(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}))
+	serve := func(encoding string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/share", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		echo.ServeHTTP(w, req)
+		return w
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`{"content": "# Hi"}`))
+	zw.Close()
+	enc, _ := zstd.NewWriter(nil)
+	zst := enc.EncodeAll([]byte(`{"content": "# Hi"}`), nil)
+
+	for encoding, body := range map[string][]byte{"gzip": gz.Bytes(), "zstd": zst, "": []byte(`{"content": "# Hi"}`)} {
+		if w := serve(encoding, body); w.Code != http.StatusOK || w.Body.String() != `{"content": "# Hi"}` {
+			t.Errorf("%q: expected decoded body, got %d %q", encoding, w.Code, w.Body.String())
+		}
+	}
+
+	bomb := enc.EncodeAll(bytes.Repeat([]byte("a"), 1<<20), nil)
+	if w := serve("zstd", bomb); w.Code != http.StatusBadRequest {
+		t.Errorf("expected oversized body to fail, got %d", w.Code)
+	}
+	if w := serve("gzip", []byte("not gzip")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid gzip to fail, got %d", w.Code)
+	}
+	if w := serve("br", []byte("x")); w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Encoding") == "" {
+		t.Errorf("expected 415 for brotli, got %d", w.Code)
+	}
+}
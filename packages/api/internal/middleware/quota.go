@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Quota enforces daily request budgets per render token, so one heavy
+// client can't crowd out everyone else's renders. Budgets reset at UTC
+// midnight and are kept in memory.
+type Quota struct {
+	limit     int
+	overrides map[string]int
+
+	mu   sync.Mutex
+	day  time.Time      // the UTC day used counts towards
+	used map[string]int // requests per token on day
+	now  func() time.Time
+}
+
+// NewQuota allows limit requests per token and day, or overrides[token]
+// where set. A limit of 0 or less is unlimited.
+func NewQuota(limit int, overrides map[string]int) *Quota {
+	return &Quota{limit: limit, overrides: overrides, used: make(map[string]int), now: time.Now}
+}
+
+// Handler counts requests that present a token and
+// rejects them with 429 once the token's budget is spent. Every counted
+// response carries X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset (unix
+// seconds). Requests without a token aren't counted. It must run behind
+// RequireToken, so clients can't mint budgets with made-up tokens.
+func (q *Quota) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := requestToken(r)
+		limit, ok := q.overrides[token]
+		if !ok {
+			limit = q.limit
+		}
+		if token == "" || limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		remaining, reset := q.take(token, limit)
+		w.Header().Set("X-Quota-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-Quota-Remaining", strconv.Itoa(max(remaining, 0)))
+		w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if remaining < 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(q.now()).Seconds())+1))
+			respondError(w, "daily render quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts a request against token and returns how many are left, -1
+// when the budget was already spent, and when it resets.
+func (q *Quota) take(token string, limit int) (int, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	day := q.now().UTC().Truncate(24 * time.Hour)
+	if !q.day.Equal(day) {
+		// A new day: drop yesterday's counts rather than keep every token
+		// ever seen.
+		q.day = day
+		q.used = make(map[string]int)
+	}
+	reset := day.Add(24 * time.Hour)
+	if q.used[token] >= limit {
+		return -1, reset
+	}
+	q.used[token]++
+	return limit - q.used[token], reset
+}
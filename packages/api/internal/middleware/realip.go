@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP replaces r.RemoteAddr with the client address from X-Forwarded-For,
+// but only when the peer is in trusted. Anyone else could set the header to
+// dodge rate limits and IP rules.
+//
+// X-Forwarded-For is read right to left, skipping trusted hops, so a client
+// can't prepend a fake address to the list its proxy appends to. Headers
+// like X-Real-IP are ignored: a proxy that only appends X-Forwarded-For
+// passes the client's own copy through. Forwarding headers from untrusted
+// peers are removed.
+func RealIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fromTrusted(r, trusted) {
+				if ip := forwardedIP(r, trusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			} else {
+				// Handlers may read these (e.g. for links they return),
+				// so untrusted copies must not get past here.
+				for _, h := range forwardingHeaders {
+					r.Header.Del(h)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var forwardingHeaders = []string{"X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host", "Forwarded"}
+
+func forwardedIP(r *http.Request, trusted []*net.IPNet) string {
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return ""
+		}
+		if !containsIP(trusted, ip) || i == 0 {
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,233 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// warmupTimeout bounds how long a page may take to load its libraries.
+const warmupTimeout = 30 * time.Second
+
+// Browser is the shared headless Chrome instance. Each renderer gets its own
+// warm tab so a slow library load or render in one does not block the others.
+type Browser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewBrowser() (*Browser, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(
+		context.Background(),
+		chromedp.NoFirstRun,
+		chromedp.NoDefaultBrowserCheck,
+		chromedp.Headless,
+		chromedp.DisableGPU,
+		chromedp.NoSandbox,
+	)
+
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	b := &Browser{
+		ctx: browserCtx,
+		cancel: func() {
+			browserCancel()
+			allocCancel()
+		},
+	}
+
+	// Start the browser now so startup failures surface here.
+	if err := chromedp.Run(browserCtx); err != nil {
+		b.cancel()
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+	return b, nil
+}
+
+// newTab opens a new tab in the browser. Cancelling the returned context
+// closes the tab.
+func (b *Browser) newTab() (context.Context, context.CancelFunc) {
+	return chromedp.NewContext(b.ctx)
+}
+
+func (b *Browser) Close() error {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	return nil
+}
+
+// page is a warm tab with a rendering library loaded. Calls are serialized;
+// sem is used instead of a mutex so waiting callers can give up.
+type page struct {
+	name      string
+	browser   *Browser
+	html      string
+	readyExpr string
+	sem       chan struct{}
+	timeout   time.Duration
+	stats     pageStats
+
+	mu     sync.Mutex // guards ctx and cancel, replaced by restart
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newPage opens html in a new tab and waits until readyExpr evaluates to true.
+// name identifies the page in Stats.
+func newPage(b *Browser, name, html, readyExpr string, timeout time.Duration) (*page, error) {
+	p := &page{name: name, browser: b, html: html, readyExpr: readyExpr, sem: make(chan struct{}, 1), timeout: timeout}
+	ctx, cancel, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	p.ctx, p.cancel = ctx, cancel
+	return p, nil
+}
+
+// open loads the page into a new tab.
+func (p *page) open() (context.Context, context.CancelFunc, error) {
+	ctx, cancel := p.browser.newTab()
+
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate("data:text/html,"+p.html),
+		chromedp.WaitReady("body"),
+	); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("warmup failed: %w", err)
+	}
+
+	var ready bool
+	for deadline := time.Now().Add(warmupTimeout); time.Now().Before(deadline); {
+		if err := chromedp.Run(ctx, chromedp.Evaluate(p.readyExpr, &ready)); err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("warmup failed: %w", err)
+		}
+		if ready {
+			return ctx, cancel, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cancel()
+	return nil, nil, fmt.Errorf("library not loaded after %s", warmupTimeout)
+}
+
+// restart replaces the tab with a freshly loaded one. A render that timed out
+// may still be running in the old tab, e.g. a synchronous Vega transform,
+// and would block every later call. It must only be called while holding
+// sem.
+func (p *page) restart() error {
+	p.mu.Lock()
+	p.cancel()
+	p.mu.Unlock()
+
+	ctx, cancel, err := p.open()
+	if err != nil {
+		return fmt.Errorf("%s restart failed: %w", p.name, err)
+	}
+	p.mu.Lock()
+	p.ctx, p.cancel = ctx, cancel
+	p.mu.Unlock()
+	p.stats.restarted()
+	log.Printf("renderer: restarted %s page", p.name)
+	return nil
+}
+
+// call evaluates expr, awaiting it if it returns a promise, and decodes the
+// result into out. It gives up when ctx is done, while queued or running.
+//
+// Once the evaluation has started, the page stays taken until it finishes
+// or times out, even if the caller gave up: the library's state in the tab
+// isn't safe to share between overlapping renders.
+func (p *page) call(ctx context.Context, expr string, out any) error {
+	queuedAt := time.Now()
+	p.stats.enqueue()
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		p.stats.dequeue(time.Since(queuedAt), false)
+		return ctx.Err()
+	}
+	p.stats.dequeue(time.Since(queuedAt), true)
+
+	type result struct {
+		raw []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-p.sem }()
+		start := time.Now()
+		raw, err := p.evaluate(expr)
+		p.stats.done(time.Since(start), err)
+		done <- result{raw, err}
+		if errors.Is(err, ErrTimeout) {
+			if err := p.restart(); err != nil {
+				log.Printf("renderer: %v", err)
+			}
+		}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return res.err
+		}
+		if err := json.Unmarshal(res.raw, out); err != nil {
+			return fmt.Errorf("decode result: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// evaluate runs expr in the tab, bounded by the page timeout. A tab lost
+// to a failed restart is reopened first. It must only be called while
+// holding sem.
+func (p *page) evaluate(expr string) ([]byte, error) {
+	p.mu.Lock()
+	tab := p.ctx
+	p.mu.Unlock()
+	if tab.Err() != nil {
+		if err := p.restart(); err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		tab = p.ctx
+		p.mu.Unlock()
+	}
+
+	runCtx, cancel := context.WithTimeout(tab, p.timeout)
+	defer cancel()
+
+	var raw []byte
+	err := chromedp.Run(runCtx,
+		chromedp.Evaluate(expr, &raw, func(ep *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return ep.WithAwaitPromise(true)
+		}),
+	)
+	if runCtx.Err() == context.DeadlineExceeded {
+		return nil, ErrTimeout
+	}
+	return raw, err
+}
+
+func (p *page) snapshot() Stats {
+	return p.stats.snapshot(p.name)
+}
+
+func (p *page) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
@@ -0,0 +1,32 @@
+package renderer
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrTimeout is returned when a render runs past Limits.Timeout.
+var ErrTimeout = errors.New("render timeout")
+
+// RenderError is diagram source the library refused, as opposed to a browser
+// failure or timeout. Line and Column are 1-based, 0 when the library didn't
+// report a position.
+type RenderError struct {
+	Renderer string // "mermaid", "vega-lite", "wavedrom" or "nomnoml"
+	Message  string
+	Line     int
+	Column   int
+	Snippet  string // the source line at Line
+}
+
+func (e *RenderError) Error() string {
+	return e.Renderer + " error: " + e.Message
+}
+
+func newRenderError(renderer, message, source string, line, column int) *RenderError {
+	e := &RenderError{Renderer: renderer, Message: message, Line: line, Column: column}
+	if lines := strings.Split(source, "\n"); line > 0 && line <= len(lines) {
+		e.Snippet = strings.TrimRight(lines[line-1], "\r")
+	}
+	return e
+}
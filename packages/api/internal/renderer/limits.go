@@ -0,0 +1,99 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrTooComplex = errors.New("diagram too complex")
+
+// Limits bound the work a single render request can cause.
+type Limits struct {
+	MaxCodeBytes int
+	MaxNodes     int
+	MaxEdges     int
+	Timeout      time.Duration
+}
+
+var DefaultLimits = Limits{
+	MaxCodeBytes: 16 << 10,
+	MaxNodes:     500,
+	MaxEdges:     1000,
+	Timeout:      10 * time.Second,
+}
+
+var (
+	// Mermaid link/message arrows: -->, ---, -.->, ==>, ->>, --x, --o, <-->, etc.
+	edgeRe = regexp.MustCompile(`<?(?:-{2,}|-\.+-|={2,})(?:>>|>|x|o)?|->>?`)
+	nodeRe = regexp.MustCompile(`[A-Za-z_][\w-]*`)
+	// Labels in quotes, node shapes and |edge text|, which hold prose rather
+	// than node ids, and :::class suffixes.
+	labelRe = regexp.MustCompile(`"[^"]*"|\[[^\]]*\]|\([^)]*\)|\{[^}]*\}|\|[^|]*\||:::[\w-]+`)
+	// Lines whose rest is free text.
+	textLineRe = regexp.MustCompile(`^(?:title|section|accTitle|accDescr)\b`)
+)
+
+// keywords are diagram syntax words that nodeRe would otherwise count.
+var keywords = map[string]bool{
+	"graph": true, "flowchart": true, "subgraph": true, "end": true, "direction": true,
+	"TD": true, "TB": true, "BT": true, "LR": true, "RL": true,
+	"sequenceDiagram": true, "participant": true, "actor": true, "as": true, "autonumber": true,
+	"activate": true, "deactivate": true, "loop": true, "alt": true, "else": true, "opt": true,
+	"par": true, "and": true, "critical": true, "break": true, "rect": true, "note": true, "Note": true,
+	"over": true, "left": true, "right": true, "of": true,
+	"classDiagram": true, "class": true, "stateDiagram": true, "stateDiagram-v2": true, "state": true,
+	"erDiagram": true, "gantt": true, "pie": true, "dateFormat": true, "axisFormat": true,
+	"style": true, "classDef": true, "linkStyle": true, "click": true, "default": true,
+}
+
+// Check rejects code that exceeds the limits. Node and edge counts are cheap
+// estimates made without parsing, good enough to refuse pathological input
+// before it reaches the browser. Nodes are the distinct identifiers outside
+// labels and message text, minus diagram keywords.
+func (l Limits) Check(code string) error {
+	if err := l.CheckSize(code); err != nil {
+		return err
+	}
+
+	edges := 0
+	nodes := map[string]struct{}{}
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		edges += len(edgeRe.FindAllString(line, -1))
+		if textLineRe.MatchString(line) {
+			continue
+		}
+		line = labelRe.ReplaceAllString(line, " ")
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i] // sequence messages, class members, gantt tasks
+		}
+		for _, n := range nodeRe.FindAllString(line, -1) {
+			if !keywords[n] {
+				nodes[n] = struct{}{}
+			}
+		}
+	}
+
+	if l.MaxEdges > 0 && edges > l.MaxEdges {
+		return fmt.Errorf("%w: ~%d edges (max %d)", ErrTooComplex, edges, l.MaxEdges)
+	}
+	if l.MaxNodes > 0 && len(nodes) > l.MaxNodes {
+		return fmt.Errorf("%w: ~%d nodes (max %d)", ErrTooComplex, len(nodes), l.MaxNodes)
+	}
+	return nil
+}
+
+// CheckSize only enforces MaxCodeBytes, for inputs such as JSON specs where
+// node and edge estimates don't apply.
+func (l Limits) CheckSize(code string) error {
+	if l.MaxCodeBytes > 0 && len(code) > l.MaxCodeBytes {
+		return fmt.Errorf("%w: %d bytes (max %d)", ErrTooComplex, len(code), l.MaxCodeBytes)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package renderer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	limits := Limits{MaxCodeBytes: 1000, MaxNodes: 10, MaxEdges: 3}
+
+	if err := limits.Check("graph TD\n  A-->B\n  B-.->C\n  C==>A"); err != nil {
+		t.Errorf("expected small diagram to pass, got %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for i := 0; i < 4; i++ {
+		b.WriteString("  A-->B\n")
+	}
+	if err := limits.Check(b.String()); !errors.Is(err, ErrTooComplex) {
+		t.Errorf("expected edge limit error, got %v", err)
+	}
+
+	if err := limits.Check(strings.Repeat("x", 1001)); !errors.Is(err, ErrTooComplex) {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+
+	if err := limits.Check("graph TD\n  n1 & n2 & n3 & n4 & n5 & n6 & n7 & n8 & n9 & n10 & n11"); !errors.Is(err, ErrTooComplex) {
+		t.Errorf("expected node limit error, got %v", err)
+	}
+
+	prose := "sequenceDiagram\n  participant A as Alice the account owner\n  A->>B: please send the quarterly report today\n" +
+		"  Note over A,B: remember the deadline is friday\ngraph LR\n  C[\"some long label text\"] -->|with edge words here| D(more words)"
+	if err := limits.Check(prose); err != nil {
+		t.Errorf("expected label words not to count as nodes, got %v", err)
+	}
+}
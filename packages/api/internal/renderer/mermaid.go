@@ -2,146 +2,138 @@ package renderer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"sync"
-	"time"
+	"regexp"
+	"strings"
+)
+
+// MermaidVersion is the default mermaid release. It is part of versioned
+// render URLs, so bump it deliberately.
+const MermaidVersion = "10.9.1"
+
+var mermaidVersionRe = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
 
-	"github.com/chromedp/chromedp"
+// EmbedFontFamily is the font used and embedded into the SVG when a render
+// asks for an embedded font, so text looks the same without it installed.
+const (
+	EmbedFontFamily = "Inter"
+	embedFontURL    = "https://cdn.jsdelivr.net/npm/@fontsource/inter@5.0.16/files/inter-latin-400-normal.woff2"
 )
 
 type MermaidRenderer struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	mu     sync.Mutex
-	ready  bool
+	page    *page
+	version string
 }
 
-func NewMermaidRenderer() (*MermaidRenderer, error) {
-	allocCtx, allocCancel := chromedp.NewExecAllocator(
-		context.Background(),
-		chromedp.NoFirstRun,
-		chromedp.NoDefaultBrowserCheck,
-		chromedp.Headless,
-		chromedp.DisableGPU,
-		chromedp.NoSandbox,
-	)
-
-	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
-
-	r := &MermaidRenderer{
-		ctx: browserCtx,
-		cancel: func() {
-			browserCancel()
-			allocCancel()
-		},
-	}
-
-	if err := r.warmup(); err != nil {
-		r.cancel()
-		return nil, fmt.Errorf("failed to warm up browser: %w", err)
+// NewMermaidRenderer opens a warm tab with the given mermaid release
+// (e.g. "10.9.1") loaded from the CDN.
+func NewMermaidRenderer(b *Browser, version string, limits Limits) (*MermaidRenderer, error) {
+	if !mermaidVersionRe.MatchString(version) {
+		return nil, fmt.Errorf("invalid mermaid version %q", version)
 	}
 
-	r.ready = true
-	return r, nil
-}
-
-func (r *MermaidRenderer) warmup() error {
-	html := `<!DOCTYPE html>
+	html := strings.NewReplacer(
+		"{{VERSION}}", version,
+		"{{FONT_URL}}", embedFontURL,
+		"{{FONT_FAMILY}}", EmbedFontFamily,
+	).Replace(`<!DOCTYPE html>
 <html>
 <head>
   <script type="module">
-    import mermaid from 'https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs';
+    import mermaid from 'https://cdn.jsdelivr.net/npm/mermaid@{{VERSION}}/dist/mermaid.esm.min.mjs';
     mermaid.initialize({ startOnLoad: false, theme: 'default', securityLevel: 'strict' });
     window.mermaid = mermaid;
     window.mermaidReady = true;
-    window.renderResult = null;
-    window.renderDone = false;
-    window.renderDiagram = async (code, theme) => {
-      window.renderDone = false;
-      window.renderResult = null;
+    window.embeddedFont = null;
+    (async () => {
+      try {
+        const res = await fetch('{{FONT_URL}}');
+        const buf = await res.arrayBuffer();
+        const face = new FontFace('{{FONT_FAMILY}}', buf);
+        await face.load();
+        document.fonts.add(face);
+        let bin = '';
+        const bytes = new Uint8Array(buf);
+        for (let i = 0; i < bytes.length; i++) bin += String.fromCharCode(bytes[i]);
+        window.embeddedFont = btoa(bin);
+      } catch (e) {
+        console.error('font load failed', e);
+      }
+    })();
+    const errorLocation = (e) => {
+      const hash = e.hash || {};
+      const loc = hash.loc || {};
+      return {
+        line: loc.first_line || (hash.line != null ? hash.line + 1 : 0),
+        column: loc.first_column != null ? loc.first_column + 1 : 0,
+      };
+    };
+    window.renderDiagram = async (code, theme, options) => {
       try {
-        mermaid.initialize({ theme: theme, securityLevel: 'strict' });
-        const result = await mermaid.render('diagram', code);
-        window.renderResult = { svg: result.svg, error: null };
+        const { embedFont, id, ...config } = options;
+        if (embedFont) {
+          if (!window.embeddedFont) throw new Error('embedded font not available');
+          config.themeVariables = { ...config.themeVariables, fontFamily: '"{{FONT_FAMILY}}", sans-serif' };
+        }
+        mermaid.initialize({ ...config, theme: theme, securityLevel: 'strict' });
+        const result = await mermaid.render(id || 'diagram', code);
+        let svg = result.svg;
+        if (embedFont) {
+          const style = '<style>@font-face{font-family:"{{FONT_FAMILY}}";src:url(data:font/woff2;base64,' + window.embeddedFont + ') format("woff2");}</style>';
+          svg = svg.replace(/<svg[^>]*>/, (tag) => tag + style);
+        }
+        return { svg: svg, error: null };
       } catch(e) {
-        window.renderResult = { svg: null, error: e.message };
+        return { svg: null, error: e.message || String(e), ...errorLocation(e) };
+      }
+    };
+    window.validateDiagram = async (code) => {
+      try {
+        await mermaid.parse(code);
+        return { valid: true, errors: [] };
+      } catch(e) {
+        return { valid: false, errors: [{ message: e.message || String(e), ...errorLocation(e) }] };
       }
-      window.renderDone = true;
     };
   </script>
 </head>
 <body><div id="diagram"></div></body>
-</html>`
-
-	var ready bool
-	err := chromedp.Run(r.ctx,
-		chromedp.Navigate("data:text/html,"+html),
-		chromedp.WaitReady("body"),
-		chromedp.Sleep(2*time.Second),
-		chromedp.EvaluateAsDevTools(`window.mermaidReady === true`, &ready),
-	)
+</html>`)
 
+	p, err := newPage(b, "mermaid-"+version, html, `window.mermaidReady === true`, limits.Timeout)
 	if err != nil {
-		return fmt.Errorf("warmup failed: %w", err)
-	}
-
-	if !ready {
-		return fmt.Errorf("mermaid library not loaded")
+		return nil, fmt.Errorf("failed to warm up browser: %w", err)
 	}
-
-	return nil
+	return &MermaidRenderer{page: p, version: version}, nil
 }
 
-func (r *MermaidRenderer) Render(code string, theme string) (string, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if !r.ready {
+// Render renders code to SVG. Cancelling ctx abandons the render.
+func (r *MermaidRenderer) Render(ctx context.Context, code string, theme string, opts MermaidOptions) (string, error) {
+	if r.page == nil {
 		return "", fmt.Errorf("renderer not ready")
 	}
 
-	// Start render
-	jsCode := fmt.Sprintf(`window.renderDiagram(%q, %q)`, code, theme)
-	err := chromedp.Run(r.ctx,
-		chromedp.Evaluate(jsCode, nil),
-	)
+	options, err := json.Marshal(opts)
 	if err != nil {
-		return "", fmt.Errorf("render call failed: %w", err)
-	}
-
-	// Poll for completion (max 30s)
-	var done bool
-	for i := 0; i < 300; i++ {
-		err = chromedp.Run(r.ctx,
-			chromedp.Evaluate(`window.renderDone`, &done),
-		)
-		if err != nil {
-			return "", fmt.Errorf("poll failed: %w", err)
-		}
-		if done {
-			break
-		}
-		time.Sleep(100 * time.Millisecond)
+		return "", err
 	}
 
-	if !done {
-		return "", fmt.Errorf("render timeout")
-	}
-
-	// Get result
 	var result struct {
-		SVG   string `json:"svg"`
-		Error string `json:"error"`
+		SVG    string `json:"svg"`
+		Error  string `json:"error"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
 	}
-	err = chromedp.Run(r.ctx,
-		chromedp.Evaluate(`window.renderResult`, &result),
-	)
-	if err != nil {
-		return "", fmt.Errorf("get result failed: %w", err)
+	prepared := opts.prepare(code)
+	jsCode := fmt.Sprintf(`window.renderDiagram(%q, %q, %s)`, prepared, theme, options)
+	if err := r.page.call(ctx, jsCode, &result); err != nil {
+		return "", fmt.Errorf("render call failed: %w", err)
 	}
 
 	if result.Error != "" {
-		return "", fmt.Errorf("mermaid error: %s", result.Error)
+		line := sourceLine(code, prepared, result.Line)
+		return "", newRenderError("mermaid", result.Error, code, line, result.Column)
 	}
 
 	if result.SVG == "" {
@@ -151,9 +143,48 @@ func (r *MermaidRenderer) Render(code string, theme string) (string, error) {
 	return result.SVG, nil
 }
 
+type ValidationError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+type ValidationResult struct {
+	Valid  bool              `json:"valid"`
+	Errors []ValidationError `json:"errors"`
+}
+
+// Validate parses diagram code with mermaid without rendering an SVG.
+func (r *MermaidRenderer) Validate(ctx context.Context, code string) (ValidationResult, error) {
+	if r.page == nil {
+		return ValidationResult{}, fmt.Errorf("renderer not ready")
+	}
+
+	var result ValidationResult
+	jsCode := fmt.Sprintf(`window.validateDiagram(%q)`, code)
+	if err := r.page.call(ctx, jsCode, &result); err != nil {
+		return ValidationResult{}, fmt.Errorf("validate call failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Version returns the mermaid release loaded in this renderer.
+func (r *MermaidRenderer) Version() string {
+	return r.version
+}
+
+// Stats returns the page's saturation signals.
+func (r *MermaidRenderer) Stats() Stats {
+	if r.page == nil {
+		return Stats{Name: "mermaid-" + r.version}
+	}
+	return r.page.snapshot()
+}
+
 func (r *MermaidRenderer) Close() error {
-	if r.cancel != nil {
-		r.cancel()
+	if r.page != nil {
+		r.page.close()
 	}
 	return nil
 }
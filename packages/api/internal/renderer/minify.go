@@ -0,0 +1,120 @@
+package renderer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	commentRe    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	newlineGapRe = regexp.MustCompile(`>\s*\n\s*<`)
+	styleRe      = regexp.MustCompile(`(?s)(<style[^>]*>)(.*?)(</style>)`)
+	cssCommentRe = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSpaceRe   = regexp.MustCompile(`\s*([{};,>])\s*`)
+	markerRe     = regexp.MustCompile(`(?s)<marker\b[^>]*\bid="([^"]+)"[^>]*>.*?</marker>`)
+	idAttrRe     = regexp.MustCompile(`\sid="([^"]+)"`)
+	segmentRe    = regexp.MustCompile(`(?s)<style[^>]*>.*?</style>|<[^>]+>`)
+	refAttrRe    = regexp.MustCompile(`(\s)(id|href|xlink:href|aria-labelledby|aria-describedby)="([^"]*)"`)
+	urlRefRe     = regexp.MustCompile(`url\(#([^)]+)\)`)
+	cssIDRe      = regexp.MustCompile(`#([A-Za-z_][\w-]*)`)
+)
+
+// MinifySVG shrinks rendered SVG markup: comments are stripped, layout
+// whitespace and <style> blocks are collapsed, unreferenced <marker>s are
+// dropped and IDs are shortened. Text content is left untouched. Shortened
+// IDs carry a prefix derived from the input, so several minified SVGs can be
+// inlined into one page.
+func MinifySVG(svg []byte) []byte {
+	s := commentRe.ReplaceAllString(string(svg), "")
+	s = newlineGapRe.ReplaceAllString(s, "><")
+	s = styleRe.ReplaceAllStringFunc(s, func(m string) string {
+		parts := styleRe.FindStringSubmatch(m)
+		return parts[1] + minifyCSS(parts[2]) + parts[3]
+	})
+	s = dropUnusedMarkers(s)
+	s = shortenIDs(s, svg)
+	return []byte(s)
+}
+
+func minifyCSS(css string) string {
+	css = cssCommentRe.ReplaceAllString(css, "")
+	css = strings.Join(strings.Fields(css), " ")
+	css = cssSpaceRe.ReplaceAllString(css, "$1")
+	css = strings.ReplaceAll(css, ": ", ":")
+	return strings.ReplaceAll(css, ";}", "}")
+}
+
+// dropUnusedMarkers removes arrowhead markers that no element references.
+// Mermaid emits every marker type for each diagram.
+func dropUnusedMarkers(s string) string {
+	return markerRe.ReplaceAllStringFunc(s, func(m string) string {
+		id := markerRe.FindStringSubmatch(m)[1]
+		if strings.Contains(s, "#"+id+")") || strings.Contains(s, "#"+id+`"`) {
+			return m
+		}
+		return ""
+	})
+}
+
+func shortenIDs(s string, original []byte) string {
+	matches := idAttrRe.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	sum := sha256.Sum256(original)
+	prefix := "m" + hex.EncodeToString(sum[:2])
+
+	ids := make(map[string]string, len(matches))
+	for _, m := range matches {
+		if _, ok := ids[m[1]]; ok {
+			continue
+		}
+		short := prefix + strconv.FormatInt(int64(len(ids)), 36)
+		if len(short) >= len(m[1]) {
+			short = m[1]
+		}
+		ids[m[1]] = short
+	}
+
+	rename := func(id string) string {
+		if short, ok := ids[id]; ok {
+			return short
+		}
+		return id
+	}
+
+	return segmentRe.ReplaceAllStringFunc(s, func(seg string) string {
+		if strings.HasPrefix(seg, "<style") {
+			return cssIDRe.ReplaceAllStringFunc(seg, func(m string) string {
+				return "#" + rename(m[1:])
+			})
+		}
+
+		seg = refAttrRe.ReplaceAllStringFunc(seg, func(m string) string {
+			parts := refAttrRe.FindStringSubmatch(m)
+			value := parts[3]
+			switch parts[2] {
+			case "id":
+				value = rename(value)
+			case "href", "xlink:href":
+				if ref, ok := strings.CutPrefix(value, "#"); ok {
+					value = "#" + rename(ref)
+				}
+			default:
+				refs := strings.Fields(value)
+				for i, ref := range refs {
+					refs[i] = rename(ref)
+				}
+				value = strings.Join(refs, " ")
+			}
+			return parts[1] + parts[2] + `="` + value + `"`
+		})
+		return urlRefRe.ReplaceAllStringFunc(seg, func(m string) string {
+			return "url(#" + rename(m[5:len(m)-1]) + ")"
+		})
+	})
+}
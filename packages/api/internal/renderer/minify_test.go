@@ -0,0 +1,63 @@
+package renderer
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const sampleSVG = `<svg id="diagram" width="100%" viewBox="0 0 100 50" aria-labelledby="chart-title-diagram">
+  <!-- generated -->
+  <title id="chart-title-diagram">A to B</title>
+  <style>
+    #diagram { font-family: "trebuchet ms", verdana; fill: #333; }
+    /* nodes */
+    #diagram .node rect { stroke: #9370DB; }
+  </style>
+  <marker id="diagram_flowchart-pointEnd" viewBox="0 0 10 10"><path d="M 0 0 L 10 5 L 0 10 z"/></marker>
+  <marker id="diagram_flowchart-circleEnd" viewBox="0 0 10 10"><circle cx="5" cy="5" r="5"/></marker>
+  <path id="L-A-B-0" marker-end="url(#diagram_flowchart-pointEnd)" d="M0,0L10,10"/>
+  <text>  keep   #diagram  spacing </text>
+</svg>`
+
+func TestMinifySVG(t *testing.T) {
+	out := string(MinifySVG([]byte(sampleSVG)))
+
+	if len(out) >= len(sampleSVG) {
+		t.Errorf("expected output to shrink: %d >= %d", len(out), len(sampleSVG))
+	}
+	if strings.Contains(out, "<!--") || strings.Contains(out, "/* nodes */") {
+		t.Errorf("comments not stripped: %s", out)
+	}
+	if strings.Contains(out, "circleEnd") {
+		t.Errorf("unused marker not dropped: %s", out)
+	}
+	if strings.Contains(out, `id="diagram"`) || strings.Contains(out, "#diagram{") {
+		t.Errorf("ids not shortened: %s", out)
+	}
+	if !strings.Contains(out, "<text>  keep   #diagram  spacing </text>") {
+		t.Errorf("text content changed: %s", out)
+	}
+
+	// References must follow the renamed ids.
+	root := idAttrRe.FindStringSubmatch(out)[1]
+	if !strings.Contains(out, "#"+root+"{") || !strings.Contains(out, "#"+root+" .node rect{") {
+		t.Errorf("style selectors not renamed to %s: %s", root, out)
+	}
+	marker := markerRe.FindStringSubmatch(out)[1]
+	if !strings.Contains(out, "url(#"+marker+")") {
+		t.Errorf("marker reference not renamed to %s: %s", marker, out)
+	}
+	title := regexpFind(`<title id="([^"]+)"`, out)
+	if !strings.Contains(out, `aria-labelledby="`+title+`"`) {
+		t.Errorf("aria reference not renamed to %s: %s", title, out)
+	}
+}
+
+func regexpFind(pattern, s string) string {
+	m := regexp.MustCompile(pattern).FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,78 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NomnomlVersion is the nomnoml release loaded into the warm page.
+const NomnomlVersion = "1.6.2"
+
+// Directives prepended to the source for the dark theme. Directives in the
+// source itself come later and win.
+const nomnomlDarkDirectives = "#stroke: #e6edf3\n#fill: #161b22; #21262d\n#background: transparent\n"
+
+// NomnomlRenderer renders nomnoml UML diagrams to SVG.
+type NomnomlRenderer struct {
+	page *page
+}
+
+func NewNomnomlRenderer(b *Browser, limits Limits) (*NomnomlRenderer, error) {
+	html := strings.ReplaceAll(`<!DOCTYPE html>
+<html>
+<head>
+  <script src="https://cdn.jsdelivr.net/npm/graphre@0.1.3/dist/graphre.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/nomnoml@{{VERSION}}/dist/nomnoml.js"></script>
+  <script>
+    window.renderNomnoml = (source) => {
+      try {
+        return { svg: nomnoml.renderSvg(source), error: null };
+      } catch (e) {
+        return { svg: null, error: e.message || String(e) };
+      }
+    };
+  </script>
+</head>
+<body></body>
+</html>`, "{{VERSION}}", NomnomlVersion)
+
+	p, err := newPage(b, "nomnoml", html, `typeof nomnoml !== 'undefined' && typeof window.renderNomnoml === 'function'`, limits.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &NomnomlRenderer{page: p}, nil
+}
+
+// Render renders source with the given theme ("dark" or "light").
+func (r *NomnomlRenderer) Render(ctx context.Context, source, theme string) (string, error) {
+	if theme == "dark" {
+		source = nomnomlDarkDirectives + source
+	}
+
+	var result struct {
+		SVG   string `json:"svg"`
+		Error string `json:"error"`
+	}
+	if err := r.page.call(ctx, fmt.Sprintf(`window.renderNomnoml(%q)`, source), &result); err != nil {
+		return "", fmt.Errorf("render call failed: %w", err)
+	}
+
+	if result.Error != "" {
+		return "", newRenderError("nomnoml", result.Error, "", 0, 0)
+	}
+	if result.SVG == "" {
+		return "", fmt.Errorf("empty SVG returned")
+	}
+	return result.SVG, nil
+}
+
+// Stats returns the page's saturation signals.
+func (r *NomnomlRenderer) Stats() Stats {
+	return r.page.snapshot()
+}
+
+func (r *NomnomlRenderer) Close() error {
+	r.page.close()
+	return nil
+}
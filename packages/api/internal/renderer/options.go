@@ -0,0 +1,195 @@
+package renderer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MermaidOptions are per-request overrides applied on top of the theme.
+type MermaidOptions struct {
+	ThemeVariables map[string]string `json:"themeVariables,omitempty"`
+	// EmbedFont renders with EmbedFontFamily and inlines it as a base64
+	// @font-face, overriding any fontFamily theme variable.
+	EmbedFont bool `json:"embedFont,omitempty"`
+	// ID is the id of the root <svg> element, "diagram" by default. SVGs
+	// inlined into the same document need distinct IDs.
+	ID string `json:"id,omitempty"`
+
+	Gantt    *GanttConfig    `json:"gantt,omitempty"`
+	Sequence *SequenceConfig `json:"sequence,omitempty"`
+}
+
+// GanttConfig overrides mermaid's gantt settings. DateFormat is a diagram
+// directive rather than config; it is inserted into the code when the
+// diagram doesn't declare one.
+type GanttConfig struct {
+	DateFormat   string `json:"dateFormat,omitempty"`
+	AxisFormat   string `json:"axisFormat,omitempty"`
+	TickInterval string `json:"tickInterval,omitempty"`
+	Weekday      string `json:"weekday,omitempty"`
+}
+
+// SequenceConfig overrides mermaid's sequence diagram settings.
+type SequenceConfig struct {
+	MirrorActors *bool `json:"mirrorActors,omitempty"`
+	Wrap         *bool `json:"wrap,omitempty"`
+	Width        int   `json:"width,omitempty"`
+	ActorMargin  *int  `json:"actorMargin,omitempty"` // pointer so 0 is kept
+}
+
+// themeVariableKeys are the mermaid theme variables clients may override.
+var themeVariableKeys = map[string]bool{
+	"background":         true,
+	"primaryColor":       true,
+	"primaryTextColor":   true,
+	"primaryBorderColor": true,
+	"secondaryColor":     true,
+	"tertiaryColor":      true,
+	"lineColor":          true,
+	"textColor":          true,
+	"mainBkg":            true,
+	"nodeBorder":         true,
+	"clusterBkg":         true,
+	"noteBkgColor":       true,
+	"noteTextColor":      true,
+	"fontFamily":         true,
+	"fontSize":           true,
+}
+
+// Values end up in the SVG's <style>, so anything that could close a rule or
+// the element is refused.
+var themeValueRe = regexp.MustCompile(`^[\w\s#(),.%'"-]{1,64}$`)
+
+// ParseThemeVariables decodes a themeVariables JSON object and checks it
+// against the allowlist. An empty string yields nil.
+func ParseThemeVariables(raw string) (map[string]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var vars map[string]string
+	if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+		return nil, fmt.Errorf("invalid themeVariables: must be a JSON object of strings")
+	}
+
+	for k, v := range vars {
+		if !themeVariableKeys[k] {
+			return nil, fmt.Errorf("invalid themeVariables: unsupported key %q (allowed: %s)", k, strings.Join(allowedKeys(), ", "))
+		}
+		if !themeValueRe.MatchString(v) {
+			return nil, fmt.Errorf("invalid themeVariables: bad value for %q", k)
+		}
+	}
+	return vars, nil
+}
+
+var (
+	// Spaces only, not \s: dateFormat is written into the diagram source,
+	// where a newline would start a line of the caller's choosing.
+	dateFormatRe    = regexp.MustCompile(`^[\w /.:,-]{1,32}$`)
+	axisFormatRe    = regexp.MustCompile(`^[%\w /.:,-]{1,32}$`)
+	tickIntervalRe  = regexp.MustCompile(`^[1-9]\d{0,2}(millisecond|second|minute|hour|day|week|month)$`)
+	weekdays        = map[string]bool{"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true}
+	ganttHeaderRe   = regexp.MustCompile(`(?m)^[ \t]*gantt[ \t]*$`)
+	dateFormatDirRe = regexp.MustCompile(`(?m)^[ \t]*dateFormat\s`)
+)
+
+// ParseDiagramConfig decodes the per-request gantt/sequence config JSON, e.g.
+// {"gantt": {"axisFormat": "%d.%m"}, "sequence": {"mirrorActors": false}}, into
+// opts. Unknown keys are rejected.
+func ParseDiagramConfig(raw string, opts *MermaidOptions) error {
+	if raw == "" {
+		return nil
+	}
+
+	var cfg struct {
+		Gantt    *GanttConfig    `json:"gantt"`
+		Sequence *SequenceConfig `json:"sequence"`
+	}
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	if g := cfg.Gantt; g != nil {
+		if g.DateFormat != "" && !dateFormatRe.MatchString(g.DateFormat) {
+			return fmt.Errorf("invalid config: bad gantt.dateFormat")
+		}
+		if g.AxisFormat != "" && !axisFormatRe.MatchString(g.AxisFormat) {
+			return fmt.Errorf("invalid config: bad gantt.axisFormat")
+		}
+		if g.TickInterval != "" && !tickIntervalRe.MatchString(g.TickInterval) {
+			return fmt.Errorf("invalid config: gantt.tickInterval must look like '1week'")
+		}
+		if g.Weekday != "" && !weekdays[g.Weekday] {
+			return fmt.Errorf("invalid config: gantt.weekday must be a lower-case day name")
+		}
+	}
+	if sq := cfg.Sequence; sq != nil {
+		if sq.Width != 0 && (sq.Width < 50 || sq.Width > 1000) {
+			return fmt.Errorf("invalid config: sequence.width must be between 50 and 1000")
+		}
+		if m := sq.ActorMargin; m != nil && (*m < 0 || *m > 500) {
+			return fmt.Errorf("invalid config: sequence.actorMargin must be between 0 and 500")
+		}
+	}
+
+	opts.Gantt = cfg.Gantt
+	opts.Sequence = cfg.Sequence
+	return nil
+}
+
+// prepare applies options that have to be expressed in the diagram code.
+func (o MermaidOptions) prepare(code string) string {
+	if o.Gantt == nil || o.Gantt.DateFormat == "" || dateFormatDirRe.MatchString(code) {
+		return code
+	}
+	loc := ganttHeaderRe.FindStringIndex(code)
+	if loc == nil {
+		return code
+	}
+	return code[:loc[1]] + "\n    dateFormat " + o.Gantt.DateFormat + code[loc[1]:]
+}
+
+// sourceLine maps a line number in prepared back to code, undoing the line
+// prepare may have inserted.
+func sourceLine(code, prepared string, line int) int {
+	if code == prepared || line == 0 {
+		return line
+	}
+	loc := ganttHeaderRe.FindStringIndex(code)
+	inserted := strings.Count(code[:loc[1]], "\n") + 2
+	switch {
+	case line > inserted:
+		return line - 1
+	case line == inserted:
+		return inserted - 1 // the directive belongs to the header line
+	}
+	return line
+}
+
+// CacheKey identifies the options for render caching, "" when empty.
+func (o MermaidOptions) CacheKey() string {
+	// Map keys are marshaled in sorted order, so equal options hash equally.
+	data, _ := json.Marshal(o)
+	if string(data) == "{}" {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func allowedKeys() []string {
+	keys := make([]string, 0, len(themeVariableKeys))
+	for k := range themeVariableKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseThemeVariables(t *testing.T) {
+	vars, err := ParseThemeVariables(`{"primaryColor": "#ff6600", "fontFamily": "'Inter', sans-serif"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["primaryColor"] != "#ff6600" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	if vars, err := ParseThemeVariables(""); err != nil || vars != nil {
+		t.Errorf("expected nil for empty input, got %v, %v", vars, err)
+	}
+
+	for _, raw := range []string{
+		`{"darkMode": "true"}`,
+		`{"primaryColor": "red; } svg { display: none"}`,
+		`{"fontSize": 16}`,
+		`not json`,
+	} {
+		if _, err := ParseThemeVariables(raw); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestMermaidOptionsCacheKey(t *testing.T) {
+	if k := (MermaidOptions{}).CacheKey(); k != "" {
+		t.Errorf("expected empty key, got %q", k)
+	}
+
+	a := MermaidOptions{ThemeVariables: map[string]string{"primaryColor": "#fff", "lineColor": "#000"}}
+	b := MermaidOptions{ThemeVariables: map[string]string{"lineColor": "#000", "primaryColor": "#fff"}}
+	c := MermaidOptions{ThemeVariables: map[string]string{"lineColor": "#111", "primaryColor": "#fff"}}
+	if a.CacheKey() != b.CacheKey() || a.CacheKey() == c.CacheKey() {
+		t.Errorf("unexpected keys: %s %s %s", a.CacheKey(), b.CacheKey(), c.CacheKey())
+	}
+
+	if k := (MermaidOptions{EmbedFont: true}).CacheKey(); k == "" {
+		t.Error("expected EmbedFont to change the cache key")
+	}
+}
+
+func TestParseDiagramConfig(t *testing.T) {
+	var opts MermaidOptions
+	err := ParseDiagramConfig(`{"gantt": {"dateFormat": "DD.MM.YYYY", "axisFormat": "%d.%m", "weekday": "monday"}, "sequence": {"mirrorActors": false, "width": 120}}`, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Gantt.AxisFormat != "%d.%m" || opts.Sequence.MirrorActors == nil || *opts.Sequence.MirrorActors {
+		t.Errorf("unexpected options: %+v %+v", opts.Gantt, opts.Sequence)
+	}
+	if opts.CacheKey() == "" {
+		t.Error("expected config to change the cache key")
+	}
+
+	if err := ParseDiagramConfig(`{"sequence": {"actorMargin": 0}}`, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(opts.Sequence); !strings.Contains(string(data), `"actorMargin":0`) {
+		t.Errorf("expected actorMargin 0 to be kept, got %s", data)
+	}
+
+	for _, raw := range []string{
+		`{"flowchart": {}}`,
+		`{"gantt": {"barHeight": 40}}`,
+		`{"gantt": {"axisFormat": "</style>"}}`,
+		`{"gantt": {"weekday": "Funday"}}`,
+		`{"gantt": {"dateFormat": "YYYY\nclick a href \"javascript:alert(1)\""}}`,
+		`{"gantt": {"tickInterval": "0day"}}`,
+		`{"sequence": {"width": 5000}}`,
+	} {
+		if err := ParseDiagramConfig(raw, &MermaidOptions{}); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestPrepareDateFormat(t *testing.T) {
+	opts := MermaidOptions{Gantt: &GanttConfig{DateFormat: "DD.MM.YYYY"}}
+
+	got := opts.prepare("gantt\n    title Plan\n    Task :a1, 01.02.2024, 3d")
+	want := "gantt\n    dateFormat DD.MM.YYYY\n    title Plan\n    Task :a1, 01.02.2024, 3d"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	declared := "gantt\n    dateFormat YYYY-MM-DD\n"
+	if got := opts.prepare(declared); got != declared {
+		t.Errorf("expected existing dateFormat to win, got %q", got)
+	}
+	if got := opts.prepare("graph TD\n  A-->B"); got != "graph TD\n  A-->B" {
+		t.Errorf("expected non-gantt code unchanged, got %q", got)
+	}
+}
+
+func TestSourceLine(t *testing.T) {
+	opts := MermaidOptions{Gantt: &GanttConfig{DateFormat: "DD.MM.YYYY"}}
+	code := "gantt\n    title Plan\n    Task :a1, 01.02.2024, 3d"
+	prepared := opts.prepare(code)
+
+	for prep, want := range map[int]int{0: 0, 1: 1, 2: 1, 3: 2, 4: 3} {
+		if got := sourceLine(code, prepared, prep); got != want {
+			t.Errorf("line %d: expected %d, got %d", prep, want, got)
+		}
+	}
+	if got := sourceLine(code, code, 3); got != 3 {
+		t.Errorf("expected unprepared lines unchanged, got %d", got)
+	}
+
+	e := newRenderError("mermaid", "Parse error", code, 3, 5)
+	if e.Snippet != "    Task :a1, 01.02.2024, 3d" || e.Error() != "mermaid error: Parse error" {
+		t.Errorf("unexpected render error %+v", e)
+	}
+}
@@ -0,0 +1,116 @@
+package renderer
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// waitSamples is how many recent queue waits QueueWaitP95 is computed over.
+const waitSamples = 256
+
+// Stats are a renderer's saturation signals at one point in time.
+type Stats struct {
+	Name        string // e.g. "mermaid-10.9.1"
+	Queued      int    // callers waiting for the page
+	Busy        bool   // a render is running
+	BusySeconds float64
+	// QueueWaitP95 is the 95th percentile time callers waited for the page,
+	// over the last waitSamples calls.
+	QueueWaitP95 time.Duration
+	// ConsecutiveFailures counts timeouts and browser errors in a row;
+	// rejected diagram source doesn't count.
+	ConsecutiveFailures int
+	// Restarts counts page reloads after timeouts; ConsecutiveRestarts
+	// only those since the last successful render.
+	Restarts            int
+	ConsecutiveRestarts int
+	// SaturatedSince is when callers started queueing without the queue
+	// draining since, zero while it is empty.
+	SaturatedSince time.Time
+}
+
+// pageStats records what page.call does. Pages serialize calls, so a
+// non-empty queue means the renderer can't keep up.
+type pageStats struct {
+	mu             sync.Mutex
+	queued         int
+	busy           bool
+	busyTotal      time.Duration
+	waits          [waitSamples]time.Duration
+	nwaits         int
+	failures       int
+	restarts       int
+	restartsInRow  int
+	saturatedSince time.Time
+}
+
+func (s *pageStats) enqueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queued == 0 && s.busy {
+		s.saturatedSince = time.Now()
+	}
+	s.queued++
+}
+
+// dequeue records a caller leaving the queue, either to run (acquired) or
+// because it gave up.
+func (s *pageStats) dequeue(wait time.Duration, acquired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued--
+	if s.queued == 0 {
+		s.saturatedSince = time.Time{}
+	}
+	s.waits[s.nwaits%waitSamples] = wait
+	s.nwaits++
+	if acquired {
+		s.busy = true
+	}
+}
+
+// done records a finished evaluation. Evaluations run to the end even when
+// their caller gave up, so every error says something about the renderer.
+func (s *pageStats) done(took time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy = false
+	s.busyTotal += took
+	if err == nil {
+		s.failures = 0
+		s.restartsInRow = 0
+	} else {
+		s.failures++
+	}
+}
+
+func (s *pageStats) restarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restarts++
+	s.restartsInRow++
+}
+
+func (s *pageStats) snapshot(name string) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waits := slices.Clone(s.waits[:min(s.nwaits, waitSamples)])
+	slices.Sort(waits)
+	var p95 time.Duration
+	if len(waits) > 0 {
+		p95 = waits[(len(waits)*95-1)/100]
+	}
+	return Stats{
+		Name:                name,
+		Queued:              s.queued,
+		Busy:                s.busy,
+		BusySeconds:         s.busyTotal.Seconds(),
+		QueueWaitP95:        p95,
+		ConsecutiveFailures: s.failures,
+		Restarts:            s.restarts,
+		ConsecutiveRestarts: s.restartsInRow,
+		SaturatedSince:      s.saturatedSince,
+	}
+}
@@ -0,0 +1,52 @@
+package renderer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPageStats(t *testing.T) {
+	var s pageStats
+
+	// An idle page: the caller runs straight away.
+	s.enqueue()
+	s.dequeue(time.Millisecond, true)
+	if st := s.snapshot("mermaid"); !st.Busy || st.Queued != 0 || !st.SaturatedSince.IsZero() {
+		t.Fatalf("unexpected stats for a running call: %+v", st)
+	}
+
+	// A second caller queues behind it.
+	s.enqueue()
+	if st := s.snapshot("mermaid"); st.Queued != 1 || st.SaturatedSince.IsZero() {
+		t.Errorf("expected a saturated queue, got %+v", st)
+	}
+	s.done(time.Second, errors.New("render timeout"))
+	s.dequeue(time.Second, true)
+	s.done(time.Second, errors.New("render timeout"))
+
+	st := s.snapshot("mermaid")
+	if st.Busy || st.Queued != 0 || !st.SaturatedSince.IsZero() {
+		t.Errorf("expected an idle page, got %+v", st)
+	}
+	if st.ConsecutiveFailures != 2 || st.BusySeconds != 2 || st.QueueWaitP95 != time.Second {
+		t.Errorf("unexpected totals: %+v", st)
+	}
+
+	s.enqueue()
+	s.dequeue(time.Second, false)
+	if st := s.snapshot("mermaid"); st.Busy || st.ConsecutiveFailures != 2 {
+		t.Errorf("callers giving up in the queue must not count, got %+v", st)
+	}
+	s.restarted()
+	s.restarted()
+	if st := s.snapshot("mermaid"); st.Restarts != 2 || st.ConsecutiveRestarts != 2 {
+		t.Errorf("expected 2 restarts, got %+v", st)
+	}
+	s.enqueue()
+	s.dequeue(0, true)
+	s.done(time.Millisecond, nil)
+	if st := s.snapshot("mermaid"); st.ConsecutiveFailures != 0 || st.ConsecutiveRestarts != 0 || st.Restarts != 2 {
+		t.Errorf("expected success to reset failures and restarts in a row, got %+v", st)
+	}
+}
@@ -0,0 +1,118 @@
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	svgTagRe  = regexp.MustCompile(`(?s)<svg\b[^>]*>`)
+	viewBoxRe = regexp.MustCompile(`\bviewBox="\s*[-\d.]+[\s,]+[-\d.]+[\s,]+([\d.]+)[\s,]+([\d.]+)\s*"`)
+	widthRe   = regexp.MustCompile(`\swidth="([\d.]+)(?:px)?"`)
+	heightRe  = regexp.MustCompile(`\sheight="([\d.]+)(?:px)?"`)
+)
+
+// SVGSize returns the intrinsic size of an SVG in pixels, rounded up. It uses
+// the root element's viewBox, falling back to absolute width/height
+// attributes. ok is false when neither is present.
+func SVGSize(svg []byte) (width, height int, ok bool) {
+	tag := svgTagRe.Find(svg)
+	if tag == nil {
+		return 0, 0, false
+	}
+
+	if m := viewBoxRe.FindSubmatch(tag); m != nil {
+		return ceil(string(m[1])), ceil(string(m[2])), true
+	}
+
+	w, h := widthRe.FindSubmatch(tag), heightRe.FindSubmatch(tag)
+	if w == nil || h == nil {
+		return 0, 0, false
+	}
+	return ceil(string(w[1])), ceil(string(h[1])), true
+}
+
+func ceil(s string) int {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return int(math.Ceil(f))
+}
+
+// CombineAdaptive nests a light and a dark render of the same diagram in one
+// SVG that shows whichever matches the reader's prefers-color-scheme. The two
+// renders must use distinct element IDs, since their styles share a document.
+func CombineAdaptive(light, dark []byte) []byte {
+	lw, lh, _ := SVGSize(light)
+	dw, dh, _ := SVGSize(dark)
+	w, h := max(lw, dw), max(lh, dh)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, w, h, w, h)
+	b.WriteString(`<style>.md-dark{display:none}@media (prefers-color-scheme:dark){.md-light{display:none}.md-dark{display:inline}}</style>`)
+	b.WriteString(`<g class="md-light">`)
+	b.Write(light)
+	b.WriteString(`</g><g class="md-dark">`)
+	b.Write(dark)
+	b.WriteString(`</g></svg>`)
+	return []byte(b.String())
+}
+
+const (
+	errorSVGWidth    = 480
+	errorSVGLineLen  = 64
+	errorSVGMaxLines = 8
+)
+
+// ErrorSVG is a placeholder image for a failed render, showing title and the
+// wrapped message, so <img> embeds degrade to a readable box instead of a
+// broken image icon. It follows prefers-color-scheme like CombineAdaptive.
+func ErrorSVG(title, message string) []byte {
+	lines := wrapText(message, errorSVGLineLen)
+	if len(lines) > errorSVGMaxLines {
+		lines = append(lines[:errorSVGMaxLines-1], "…")
+	}
+	h := 48 + 18*len(lines)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`, errorSVGWidth, h, errorSVGWidth, h)
+	b.WriteString(`<style>rect{fill:#fff5f5;stroke:#e5534b}text{fill:#82071e;font:13px ui-monospace,Menlo,monospace}.t{font-weight:bold;font-family:sans-serif}` +
+		`@media (prefers-color-scheme:dark){rect{fill:#2d1117;stroke:#f47067}text{fill:#ffa198}}</style>`)
+	fmt.Fprintf(&b, `<title>%s</title>`, html.EscapeString(title))
+	fmt.Fprintf(&b, `<rect x="0.5" y="0.5" width="%d" height="%d" rx="6"/>`, errorSVGWidth-1, h-1)
+	fmt.Fprintf(&b, `<text class="t" x="16" y="26">%s</text>`, html.EscapeString(title))
+	for i, l := range lines {
+		fmt.Fprintf(&b, `<text x="16" y="%d" xml:space="preserve">%s</text>`, 50+18*i, html.EscapeString(l))
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+// wrapText splits s into lines of at most n runes, breaking at spaces where
+// possible.
+func wrapText(s string, n int) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		var line []rune
+		for _, word := range strings.Fields(para) {
+			for w := []rune(word); len(w) > 0; {
+				if len(line) > 0 && len(line)+1+len(w) > n {
+					lines = append(lines, string(line))
+					line = nil
+				}
+				if len(line) > 0 {
+					line = append(line, ' ')
+				}
+				take := min(len(w), n-len(line))
+				line = append(line, w[:take]...)
+				w = w[take:]
+			}
+		}
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines
+}
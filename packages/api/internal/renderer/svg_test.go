@@ -0,0 +1,63 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSVGSize(t *testing.T) {
+	tests := []struct {
+		svg  string
+		w, h int
+		ok   bool
+	}{
+		{`<svg id="diagram" width="100%" style="max-width: 210.5px;" viewBox="-8 -8 210.5 174" role="graphics-document">`, 211, 174, true},
+		{`<svg xmlns="http://www.w3.org/2000/svg" width="640" height="400">`, 640, 400, true},
+		{`<svg width="100%">`, 0, 0, false},
+		{`<div></div>`, 0, 0, false},
+	}
+	for _, tt := range tests {
+		w, h, ok := SVGSize([]byte(tt.svg))
+		if w != tt.w || h != tt.h || ok != tt.ok {
+			t.Errorf("SVGSize(%q) = %d, %d, %v; want %d, %d, %v", tt.svg, w, h, ok, tt.w, tt.h, tt.ok)
+		}
+	}
+}
+
+func TestCombineAdaptive(t *testing.T) {
+	light := []byte(`<svg id="diagram-light" viewBox="0 0 100 40"></svg>`)
+	dark := []byte(`<svg id="diagram-dark" viewBox="0 0 102 40"></svg>`)
+
+	out := CombineAdaptive(light, dark)
+
+	w, h, ok := SVGSize(out)
+	if !ok || w != 102 || h != 40 {
+		t.Errorf("expected combined size 102x40, got %dx%d", w, h)
+	}
+	for _, want := range []string{"prefers-color-scheme:dark", `class="md-light"><svg id="diagram-light"`, `class="md-dark"><svg id="diagram-dark"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected output to contain %q: %s", want, out)
+		}
+	}
+}
+
+func TestErrorSVG(t *testing.T) {
+	svg := ErrorSVG("mermaid syntax error", "Parse error on line 2:\n<script>A-->\n"+strings.Repeat("x", 200))
+
+	if w, h, ok := SVGSize(svg); !ok || w != 480 || h <= 48 {
+		t.Errorf("unexpected size %dx%d", w, h)
+	}
+	if strings.Contains(string(svg), "<script>") {
+		t.Error("message must be escaped")
+	}
+	if !strings.Contains(string(svg), "&lt;script&gt;A--&gt;") {
+		t.Errorf("expected escaped message line, got %s", svg)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	got := strings.Join(wrapText("aaa bbb cccccccc\n\nd", 7), "|")
+	if want := "aaa bbb|ccccccc|c|d"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package renderer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// VegaLiteVersion is the vega-lite release loaded into the warm page.
+const VegaLiteVersion = "5.21.0"
+
+// VegaLiteRenderer renders Vega-Lite JSON specs to SVG or PNG. External data
+// URLs in specs are refused; data must be inlined with "values".
+type VegaLiteRenderer struct {
+	page *page
+}
+
+func NewVegaLiteRenderer(b *Browser, limits Limits) (*VegaLiteRenderer, error) {
+	html := strings.ReplaceAll(`<!DOCTYPE html>
+<html>
+<head>
+  <script src="https://cdn.jsdelivr.net/npm/vega@5.30.0/build/vega.min.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/vega-lite@{{VERSION}}/build/vega-lite.min.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/vega-themes@2.15.0/build/vega-themes.min.js"></script>
+  <script>
+    const loader = vega.loader();
+    loader.load = async () => { throw new Error('external data is not allowed'); };
+    window.renderVegaLite = async (spec, theme, format) => {
+      let view;
+      try {
+        const config = theme === 'dark' ? vegaThemes.dark : {};
+        const compiled = vegaLite.compile(JSON.parse(spec), { config }).spec;
+        view = new vega.View(vega.parse(compiled), { renderer: 'none', loader });
+        const out = format === 'png' ? await view.toImageURL('png', 2) : await view.toSVG();
+        return { output: out, error: null };
+      } catch (e) {
+        return { output: null, error: e.message || String(e) };
+      } finally {
+        if (view) view.finalize();
+      }
+    };
+  </script>
+</head>
+<body></body>
+</html>`, "{{VERSION}}", VegaLiteVersion)
+
+	p, err := newPage(b, "vega-lite", html, `typeof window.renderVegaLite === 'function' && typeof vegaLite !== 'undefined'`, limits.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &VegaLiteRenderer{page: p}, nil
+}
+
+// Render renders spec with the given theme ("dark" or "light"). format is
+// "svg" or "png"; PNGs are rendered at 2x scale.
+func (r *VegaLiteRenderer) Render(ctx context.Context, spec, theme, format string) ([]byte, error) {
+	var result struct {
+		Output string `json:"output"`
+		Error  string `json:"error"`
+	}
+	jsCode := fmt.Sprintf(`window.renderVegaLite(%q, %q, %q)`, spec, theme, format)
+	if err := r.page.call(ctx, jsCode, &result); err != nil {
+		return nil, fmt.Errorf("render call failed: %w", err)
+	}
+
+	if result.Error != "" {
+		return nil, newRenderError("vega-lite", result.Error, "", 0, 0)
+	}
+	if result.Output == "" {
+		return nil, fmt.Errorf("empty output returned")
+	}
+
+	if format == "png" {
+		data, ok := strings.CutPrefix(result.Output, "data:image/png;base64,")
+		if !ok {
+			return nil, fmt.Errorf("unexpected image output")
+		}
+		return base64.StdEncoding.DecodeString(data)
+	}
+	return []byte(result.Output), nil
+}
+
+// Stats returns the page's saturation signals.
+func (r *VegaLiteRenderer) Stats() Stats {
+	return r.page.snapshot()
+}
+
+func (r *VegaLiteRenderer) Close() error {
+	r.page.close()
+	return nil
+}
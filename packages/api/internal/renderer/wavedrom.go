@@ -0,0 +1,75 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WaveDromVersion is the wavedrom release loaded into the warm page.
+const WaveDromVersion = "3.5.0"
+
+// WaveDromRenderer renders WaveJSON timing diagrams to SVG. Sources must be
+// strict JSON; the JavaScript-literal form WaveDrom accepts in browsers is not
+// evaluated.
+type WaveDromRenderer struct {
+	page *page
+}
+
+func NewWaveDromRenderer(b *Browser, limits Limits) (*WaveDromRenderer, error) {
+	html := strings.ReplaceAll(`<!DOCTYPE html>
+<html>
+<head>
+  <script src="https://cdn.jsdelivr.net/npm/wavedrom@{{VERSION}}/skins/default.js"></script>
+  <script src="https://cdn.jsdelivr.net/npm/wavedrom@{{VERSION}}/wavedrom.min.js"></script>
+  <script>
+    window.renderWaveDrom = (source) => {
+      const out = document.getElementById('WaveDrom_Display_0');
+      out.innerHTML = '';
+      try {
+        WaveDrom.RenderWaveForm(0, JSON.parse(source), 'WaveDrom_Display_', false);
+        const svg = out.querySelector('svg');
+        return { svg: svg ? svg.outerHTML : null, error: null };
+      } catch (e) {
+        return { svg: null, error: e.message || String(e) };
+      }
+    };
+  </script>
+</head>
+<body><div id="WaveDrom_Display_0"></div></body>
+</html>`, "{{VERSION}}", WaveDromVersion)
+
+	p, err := newPage(b, "wavedrom", html, `typeof WaveDrom !== 'undefined' && typeof window.renderWaveDrom === 'function'`, limits.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &WaveDromRenderer{page: p}, nil
+}
+
+func (r *WaveDromRenderer) Render(ctx context.Context, source string) (string, error) {
+	var result struct {
+		SVG   string `json:"svg"`
+		Error string `json:"error"`
+	}
+	if err := r.page.call(ctx, fmt.Sprintf(`window.renderWaveDrom(%q)`, source), &result); err != nil {
+		return "", fmt.Errorf("render call failed: %w", err)
+	}
+
+	if result.Error != "" {
+		return "", newRenderError("wavedrom", result.Error, "", 0, 0)
+	}
+	if result.SVG == "" {
+		return "", fmt.Errorf("empty SVG returned")
+	}
+	return result.SVG, nil
+}
+
+// Stats returns the page's saturation signals.
+func (r *WaveDromRenderer) Stats() Stats {
+	return r.page.snapshot()
+}
+
+func (r *WaveDromRenderer) Close() error {
+	r.page.close()
+	return nil
+}
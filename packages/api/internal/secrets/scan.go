@@ -0,0 +1,67 @@
+// Package secrets finds likely credentials in markdown, so writes can warn
+// about or refuse content pasted straight from a terminal.
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Finding is a likely credential. The matched text is left out so responses
+// and logs don't repeat the secret.
+type Finding struct {
+	Rule string `json:"rule"`
+	Line int    `json:"line"`
+}
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var rules = []rule{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bgithub_pat_[A-Za-z0-9_]{22,}`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+// Scan returns the likely credentials in content, at most one per rule and
+// line.
+func Scan(content string) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(content, "\n") {
+		for _, r := range rules {
+			if r.re.MatchString(line) {
+				findings = append(findings, Finding{Rule: r.name, Line: i + 1})
+			}
+		}
+	}
+	return findings
+}
+
+// Policy is what a write does with findings.
+type Policy string
+
+const (
+	PolicyOff   Policy = "off"   // don't scan
+	PolicyWarn  Policy = "warn"  // save, and report findings in the response
+	PolicyBlock Policy = "block" // refuse the write
+)
+
+// ParsePolicy parses "off", "warn" or "block"; empty means warn.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case "":
+		return PolicyWarn, nil
+	case PolicyOff, PolicyWarn, PolicyBlock:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid secret scan policy %q, must be off, warn or block", s)
+	}
+}
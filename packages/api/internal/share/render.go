@@ -0,0 +1,98 @@
+package share
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+
+	"github.com/dnl-fm/md/packages/api/internal/markdown"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var pageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="robots" content="noindex">
+  <title>{{.Title}}</title>
+  <style>
+    body { max-width: 760px; margin: 2rem auto; padding: 0 1rem; font: 16px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; color: #24292f; }
+    pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; border-radius: 6px; }
+    code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+    table { border-collapse: collapse; } th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; }
+    img { max-width: 100%; }
+    footer { margin-top: 3rem; font-size: 0.85em; color: #57606a; }
+    @media (prefers-color-scheme: dark) {
+      body { background: #0d1117; color: #e6edf3; }
+      pre { background: #161b22; }
+      th, td { border-color: #30363d; }
+      a { color: #58a6ff; }
+      footer { color: #8b949e; }
+    }
+  </style>
+</head>
+<body>
+<main>{{.Body}}</main>
+<footer>Expires {{.ExpiresAt.Format "2006-01-02 15:04 MST"}} · <a href="{{.ID}}/raw">raw</a></footer>
+</body>
+</html>
+`))
+
+// Renderer renders shares as HTML pages under a Policy. Raw HTML in the
+// markdown is dropped unless the policy allows some, and the output is
+// sanitized either way, so pastes can't inject scripts into readers'
+// browsers.
+type Renderer struct {
+	policy    Policy
+	md        goldmark.Markdown
+	sanitizer *bluemonday.Policy
+}
+
+func NewRenderer(policy Policy) (*Renderer, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []goldmark.Option{goldmark.WithExtensions(extension.GFM)}
+	if policy.allowsRawHTML() {
+		opts = append(opts, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+	return &Renderer{
+		policy:    policy,
+		md:        goldmark.New(opts...),
+		sanitizer: policy.sanitizer(),
+	}, nil
+}
+
+// ContentSecurityPolicy is the CSP header to serve pages with.
+func (r *Renderer) ContentSecurityPolicy() string {
+	return r.policy.ContentSecurityPolicy()
+}
+
+func (r *Renderer) RenderPage(w io.Writer, sh *Share) error {
+	var body bytes.Buffer
+	if err := r.md.Convert([]byte(sh.Content), &body); err != nil {
+		return err
+	}
+
+	return pageTemplate.Execute(w, map[string]any{
+		"ID":        sh.ID,
+		"Title":     Title(sh.Content),
+		"Body":      template.HTML(r.sanitizer.SanitizeBytes(body.Bytes())),
+		"ExpiresAt": sh.ExpiresAt,
+	})
+}
+
+// Title returns the first heading of content, or a generic title.
+func Title(content string) string {
+	for _, l := range markdown.SplitLines(content) {
+		if level, text := markdown.Heading(l); level > 0 && text != "" {
+			return text
+		}
+	}
+	return "Shared document"
+}
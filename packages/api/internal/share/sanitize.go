@@ -0,0 +1,127 @@
+package share
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Policy decides which HTML shared pages may contain. The zero value drops
+// raw HTML from the markdown and allows images from any https host.
+type Policy struct {
+	// Tags are raw HTML elements allowed in the markdown (e.g. "details",
+	// "summary", "kbd"), without attributes. Scriptable and form elements
+	// are refused.
+	Tags []string
+	// IframeHosts are hosts iframes may embed over https (e.g.
+	// "www.youtube-nocookie.com"). Iframes are sandboxed.
+	IframeHosts []string
+	// ImageHosts restricts images to these https hosts; empty allows any
+	// https or data: image.
+	ImageHosts []string
+}
+
+// forbiddenTags can run script, submit data or restyle the page, whatever
+// the attributes.
+var forbiddenTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "form": true, "input": true,
+	"button": true, "textarea": true, "select": true, "option": true, "link": true,
+	"meta": true, "base": true, "svg": true, "math": true, "template": true,
+	"html": true, "head": true, "body": true, "title": true, "noscript": true,
+}
+
+var (
+	tagRe  = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	hostRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]+)?$`)
+)
+
+// markdownTags are the elements goldmark's GFM output uses.
+var markdownTags = []string{
+	"p", "h1", "h2", "h3", "h4", "h5", "h6", "em", "strong", "del", "code", "pre",
+	"blockquote", "ul", "ol", "li", "hr", "br", "table", "thead", "tbody", "tr", "th", "td",
+}
+
+// Validate reports tags and hosts the policy can't express safely.
+func (p Policy) Validate() error {
+	for _, t := range p.Tags {
+		if !tagRe.MatchString(t) || forbiddenTags[t] {
+			return fmt.Errorf("HTML tag %q is not allowed in shares", t)
+		}
+	}
+	for _, h := range append(append([]string{}, p.IframeHosts...), p.ImageHosts...) {
+		if !hostRe.MatchString(h) {
+			return fmt.Errorf("invalid host %q", h)
+		}
+	}
+	return nil
+}
+
+// allowsRawHTML reports whether markdown HTML passes through to the
+// sanitizer instead of being dropped by goldmark.
+func (p Policy) allowsRawHTML() bool {
+	return len(p.Tags) > 0 || len(p.IframeHosts) > 0
+}
+
+func (p Policy) sanitizer() *bluemonday.Policy {
+	s := bluemonday.NewPolicy()
+	s.AllowElements(markdownTags...)
+	s.AllowElements(p.Tags...)
+
+	s.AllowStandardURLs()
+	s.AllowAttrs("href", "title").OnElements("a")
+	s.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	s.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	s.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+	s.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	s.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	s.AllowAttrs("checked", "disabled").OnElements("input")
+
+	s.AllowAttrs("alt", "title").OnElements("img")
+	if len(p.ImageHosts) > 0 {
+		s.AllowAttrs("src").Matching(hostsURLRe(p.ImageHosts)).OnElements("img")
+	} else {
+		s.AllowDataURIImages()
+		s.AllowAttrs("src").OnElements("img")
+	}
+
+	if len(p.IframeHosts) > 0 {
+		s.AllowAttrs("src").Matching(hostsURLRe(p.IframeHosts)).OnElements("iframe")
+		s.AllowAttrs("width", "height").Matching(bluemonday.Integer).OnElements("iframe")
+		s.AllowAttrs("title", "allowfullscreen").OnElements("iframe")
+		s.RequireSandboxOnIFrame(bluemonday.SandboxAllowScripts, bluemonday.SandboxAllowSameOrigin, bluemonday.SandboxAllowPopups)
+	}
+	return s
+}
+
+// ContentSecurityPolicy is the CSP header matching the policy, so the
+// browser enforces the same image and iframe rules.
+func (p Policy) ContentSecurityPolicy() string {
+	img := "https: data:"
+	if len(p.ImageHosts) > 0 {
+		img = hostSources(p.ImageHosts)
+	}
+	csp := "default-src 'none'; style-src 'unsafe-inline'; img-src " + img
+	if len(p.IframeHosts) > 0 {
+		csp += "; frame-src " + hostSources(p.IframeHosts)
+	}
+	return csp
+}
+
+func hostsURLRe(hosts []string) *regexp.Regexp {
+	quoted := make([]string, len(hosts))
+	for i, h := range hosts {
+		quoted[i] = regexp.QuoteMeta(h)
+	}
+	return regexp.MustCompile(`^https://(` + strings.Join(quoted, "|") + `)/`)
+}
+
+func hostSources(hosts []string) string {
+	sources := make([]string, len(hosts))
+	for i, h := range hosts {
+		sources[i] = "https://" + h
+	}
+	return strings.Join(sources, " ")
+}
@@ -0,0 +1,191 @@
+package share
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MaxContentBytes = 512 << 10
+	DefaultTTL      = 7 * 24 * time.Hour
+	MaxTTL          = 30 * 24 * time.Hour
+)
+
+var (
+	ErrNotFound     = errors.New("share not found")
+	ErrInvalidToken = errors.New("invalid delete token")
+
+	idRe       = regexp.MustCompile(`^[a-z2-7]{12}$`)
+	idEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// Share is an anonymous markdown snippet with an expiry.
+type Share struct {
+	ID              string    `json:"id"`
+	Content         string    `json:"content"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	DeleteTokenHash string    `json:"delete_token_hash"`
+}
+
+// Store keeps shares as JSON files in a directory, one file per share.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create share dir: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Create stores content and returns the share along with its plaintext
+// delete token, which is only ever returned here.
+func (s *Store) Create(content string, ttl time.Duration) (*Share, string, error) {
+	if len(content) > MaxContentBytes {
+		return nil, "", fmt.Errorf("content too large (max %d bytes)", MaxContentBytes)
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if ttl > MaxTTL {
+		return nil, "", fmt.Errorf("ttl too long (max %s)", MaxTTL)
+	}
+
+	idBytes, err := randomBytes(8)
+	if err != nil {
+		return nil, "", err
+	}
+	tokenBytes, err := randomBytes(24)
+	if err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	now := time.Now().UTC()
+	sh := &Share{
+		ID:              strings.ToLower(idEncoding.EncodeToString(idBytes))[:12],
+		Content:         content,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(ttl),
+		DeleteTokenHash: hashToken(token),
+	}
+
+	data, err := json.Marshal(sh)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.WriteFile(s.path(sh.ID), data, 0o640); err != nil {
+		return nil, "", fmt.Errorf("failed to write share: %w", err)
+	}
+
+	return sh, token, nil
+}
+
+func (s *Store) Get(id string) (*Share, error) {
+	if !idRe.MatchString(id) {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var sh Share
+	if err := json.Unmarshal(data, &sh); err != nil {
+		return nil, fmt.Errorf("corrupt share %s: %w", id, err)
+	}
+	if time.Now().After(sh.ExpiresAt) {
+		os.Remove(s.path(id))
+		return nil, ErrNotFound
+	}
+	return &sh, nil
+}
+
+func (s *Store) Delete(id, token string) error {
+	sh, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(sh.DeleteTokenHash)) != 1 {
+		return ErrInvalidToken
+	}
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PurgeExpired removes all expired shares and returns how many were deleted.
+func (s *Store) PurgeExpired() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !idRe.MatchString(id) {
+			continue
+		}
+		if _, err := s.Get(id); errors.Is(err, ErrNotFound) {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// StartJanitor purges expired shares every interval until stop is closed.
+func (s *Store) StartJanitor(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if n, err := s.PurgeExpired(); err != nil {
+					log.Printf("share janitor: %v", err)
+				} else if n > 0 {
+					log.Printf("share janitor: purged %d expired shares", n)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,140 @@
+package share
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreLifecycle(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sh, token, err := s.Create("# Hello", time.Hour)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if strings.Contains(sh.DeleteTokenHash, token) {
+		t.Error("delete token must not be stored in plaintext")
+	}
+
+	got, err := s.Get(sh.ID)
+	if err != nil || got.Content != "# Hello" {
+		t.Fatalf("expected stored share, got %+v, %v", got, err)
+	}
+
+	if err := s.Delete(sh.ID, "wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+	if err := s.Delete(sh.ID, token); err != nil {
+		t.Errorf("delete failed: %v", err)
+	}
+	if _, err := s.Get(sh.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewStore(dir)
+
+	sh, _, err := s.Create("expiring", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if n, err := s.PurgeExpired(); err != nil || n != 1 {
+		t.Errorf("expected 1 purged share, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(s.path(sh.ID)); !os.IsNotExist(err) {
+		t.Error("expected expired share file to be removed")
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	s, _ := NewStore(t.TempDir())
+
+	if _, _, err := s.Create(strings.Repeat("a", MaxContentBytes+1), 0); err == nil {
+		t.Error("expected error for oversized content")
+	}
+	if _, _, err := s.Create("a", MaxTTL+time.Hour); err == nil {
+		t.Error("expected error for ttl above max")
+	}
+	if _, err := s.Get("../../etc/passwd"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for invalid id, got %v", err)
+	}
+}
+
+func TestRenderPageDropsRawHTML(t *testing.T) {
+	var b strings.Builder
+	sh := &Share{ID: "abc", Content: "# Title\n\n<script>alert(1)</script>\n", ExpiresAt: time.Now()}
+
+	r, _ := NewRenderer(Policy{})
+	if err := r.RenderPage(&b, sh); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "<script>alert") {
+		t.Error("raw HTML must not be rendered")
+	}
+	if !strings.Contains(b.String(), "<title>Title</title>") {
+		t.Error("expected title from first heading")
+	}
+}
+
+func TestRenderPagePolicy(t *testing.T) {
+	r, err := NewRenderer(Policy{
+		Tags:        []string{"details", "summary"},
+		IframeHosts: []string{"www.youtube-nocookie.com"},
+		ImageHosts:  []string{"images.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "<details><summary>More</summary>\n\nhidden\n\n</details>\n\n" +
+		"<script>alert(1)</script>\n\n" +
+		"<p onclick=\"alert(1)\">click</p>\n\n" +
+		`<iframe src="https://www.youtube-nocookie.com/embed/x"></iframe>` + "\n\n" +
+		`<iframe src="https://evil.example/x"></iframe>` + "\n\n" +
+		"![ok](https://images.example.com/a.png) ![no](https://elsewhere.example/b.png)\n\n" +
+		"[bad](javascript:alert(1))\n"
+
+	var b strings.Builder
+	if err := r.RenderPage(&b, &Share{ID: "abc", Content: content, ExpiresAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{"<details>", "<summary>More</summary>", `src="https://www.youtube-nocookie.com/embed/x"`, "sandbox=", `src="https://images.example.com/a.png"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	for _, bad := range []string{"<script>alert", "onclick", "evil.example", "elsewhere.example", "javascript:"} {
+		if strings.Contains(out, bad) {
+			t.Errorf("unexpected %q in output", bad)
+		}
+	}
+
+	csp := r.ContentSecurityPolicy()
+	if !strings.Contains(csp, "frame-src https://www.youtube-nocookie.com") || !strings.Contains(csp, "img-src https://images.example.com") {
+		t.Errorf("unexpected CSP %q", csp)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	for _, p := range []Policy{
+		{Tags: []string{"script"}},
+		{Tags: []string{"a b"}},
+		{IframeHosts: []string{"https://example.com"}},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected %+v to be refused", p)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package spell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dnl-fm/md/packages/api/internal/markdown"
+)
+
+const (
+	maxSuggestions = 5
+	// maxSuggestWord bounds the edit-distance search, which builds a few
+	// hundred candidates the length of the word for every character.
+	maxSuggestWord = 40
+	// maxMisspellings caps the report for one document.
+	maxMisspellings = 1000
+)
+
+var (
+	languageRe = regexp.MustCompile(`^[A-Za-z]{2,3}(?:[_-][A-Za-z0-9]{2,8})?$`)
+	// Spans that are never spell checked: inline code, URLs, autolinks, link
+	// targets, HTML tags and e-mail addresses.
+	skipRe = regexp.MustCompile("`[^`]*`|https?://\\S+|www\\.\\S+|<[^>]*>|\\]\\([^)]*\\)|\\S+@\\S+\\.\\S+")
+	wordRe = regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`)
+)
+
+type Misspelling struct {
+	Word        string   `json:"word"`
+	Offset      int      `json:"offset"` // byte offset into the content
+	Line        int      `json:"line"`
+	Column      int      `json:"column"` // 1-based, in characters
+	Suggestions []string `json:"suggestions"`
+}
+
+// Registry lazily loads hunspell dictionaries from a directory containing
+// <lang>.aff/<lang>.dic pairs (e.g. en_US.aff, en_US.dic).
+type Registry struct {
+	dir   string
+	mu    sync.Mutex
+	dicts map[string]*Dictionary
+}
+
+func NewRegistry(dir string) *Registry {
+	return &Registry{dir: dir, dicts: make(map[string]*Dictionary)}
+}
+
+// Languages lists the dictionaries available in the registry directory.
+func (r *Registry) Languages() []string {
+	matches, _ := filepath.Glob(filepath.Join(r.dir, "*.dic"))
+	var langs []string
+	for _, m := range matches {
+		lang := strings.TrimSuffix(filepath.Base(m), ".dic")
+		if _, err := os.Stat(filepath.Join(r.dir, lang+".aff")); err == nil {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func (r *Registry) Get(lang string) (*Dictionary, error) {
+	if !languageRe.MatchString(lang) {
+		return nil, fmt.Errorf("invalid language %q", lang)
+	}
+	lang = strings.ReplaceAll(lang, "-", "_")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d, ok := r.dicts[lang]; ok {
+		return d, nil
+	}
+
+	d, err := LoadHunspell(filepath.Join(r.dir, lang))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no dictionary for language %q", lang)
+		}
+		return nil, err
+	}
+	r.dicts[lang] = d
+	return d, nil
+}
+
+// Check returns up to maxMisspellings misspelled words in content. Fenced
+// code, front matter, inline code, URLs and HTML are skipped; custom words
+// are always accepted.
+func Check(d *Dictionary, content string, custom []string) []Misspelling {
+	accepted := make(map[string]struct{}, len(custom))
+	for _, w := range custom {
+		accepted[strings.ToLower(w)] = struct{}{}
+	}
+
+	misspellings := []Misspelling{}
+	offset := 0
+	for _, l := range markdown.SplitLines(content) {
+		lineStart := offset
+		offset += len(l.Text) + 1
+		if offset-1 < len(content) && content[offset-1] == '\r' {
+			offset++
+		}
+		if l.InFence || l.Fence || l.FrontMatter {
+			continue
+		}
+
+		text := skipRe.ReplaceAllStringFunc(l.Text, func(s string) string {
+			return strings.Repeat(" ", len(s))
+		})
+
+		for _, loc := range wordRe.FindAllStringIndex(text, -1) {
+			word := text[loc[0]:loc[1]]
+			if skipWord(word) {
+				continue
+			}
+			if _, ok := accepted[strings.ToLower(word)]; ok || d.Contains(word) {
+				continue
+			}
+			misspellings = append(misspellings, Misspelling{
+				Word:        word,
+				Offset:      lineStart + loc[0],
+				Line:        l.Number,
+				Column:      utf8.RuneCountInString(l.Text[:loc[0]]) + 1,
+				Suggestions: d.Suggest(word),
+			})
+			if len(misspellings) == maxMisspellings {
+				return misspellings
+			}
+		}
+	}
+	return misspellings
+}
+
+// skipWord ignores single letters and acronyms.
+func skipWord(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return true
+	}
+	for _, r := range word {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Suggest returns known words within one edit (insert, delete, replace or
+// transpose) of word. Words longer than maxSuggestWord get none.
+func (d *Dictionary) Suggest(word string) []string {
+	suggestions := []string{}
+	if utf8.RuneCountInString(word) > maxSuggestWord {
+		return suggestions
+	}
+
+	letters := strings.ToLower(d.try)
+	if letters == "" {
+		letters = "abcdefghijklmnopqrstuvwxyz"
+	}
+	var alphabet []rune
+	for _, r := range letters {
+		if !slices.Contains(alphabet, r) {
+			alphabet = append(alphabet, r)
+		}
+	}
+
+	runes := []rune(strings.ToLower(word))
+	add := func(candidate []rune) {
+		if len(suggestions) >= maxSuggestions {
+			return
+		}
+		s := string(candidate)
+		if _, ok := d.words[s]; ok {
+			s = matchCase(word, s)
+		} else if _, ok := d.words[titleCase(s)]; ok {
+			s = titleCase(s)
+		} else {
+			return
+		}
+		if !slices.Contains(suggestions, s) {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	for i := 0; i < len(runes)-1; i++ {
+		c := append([]rune{}, runes...)
+		c[i], c[i+1] = c[i+1], c[i]
+		add(c)
+	}
+	for i := range runes {
+		add(append(append([]rune{}, runes[:i]...), runes[i+1:]...))
+	}
+	for i := range runes {
+		for _, a := range alphabet {
+			if a == runes[i] {
+				continue
+			}
+			c := append([]rune{}, runes...)
+			c[i] = a
+			add(c)
+		}
+	}
+	for i := 0; i <= len(runes); i++ {
+		for _, a := range alphabet {
+			c := append(append(append([]rune{}, runes[:i]...), a), runes[i:]...)
+			add(c)
+		}
+	}
+	return suggestions
+}
+
+func matchCase(original, suggestion string) string {
+	if r, _ := utf8.DecodeRuneInString(original); unicode.IsUpper(r) {
+		return titleCase(suggestion)
+	}
+	return suggestion
+}
+
+func titleCase(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
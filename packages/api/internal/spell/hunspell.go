@@ -0,0 +1,254 @@
+package spell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// affix is a single PFX/SFX rule from a hunspell .aff file.
+type affix struct {
+	flag         string
+	prefix       bool
+	crossProduct bool
+	strip        string
+	add          string
+	condition    *regexp.Regexp
+}
+
+type affixFile struct {
+	flagMode string // "", "long" or "num"
+	try      string
+	affixes  map[string][]affix
+}
+
+// Dictionary is the expanded set of word forms from a hunspell .dic/.aff pair.
+type Dictionary struct {
+	words map[string]struct{}
+	try   string
+}
+
+// LoadHunspell reads a hunspell dictionary (basePath.aff + basePath.dic) and
+// expands prefix/suffix rules into a set of valid word forms. Files must be
+// UTF-8; compounding and the more exotic affix options are not supported.
+func LoadHunspell(basePath string) (*Dictionary, error) {
+	aff, err := os.Open(basePath + ".aff")
+	if err != nil {
+		return nil, err
+	}
+	defer aff.Close()
+
+	af, err := parseAff(aff)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s.aff: %w", basePath, err)
+	}
+
+	dic, err := os.Open(basePath + ".dic")
+	if err != nil {
+		return nil, err
+	}
+	defer dic.Close()
+
+	d := &Dictionary{words: make(map[string]struct{}), try: af.try}
+	if err := d.loadDic(dic, af); err != nil {
+		return nil, fmt.Errorf("parse %s.dic: %w", basePath, err)
+	}
+	return d, nil
+}
+
+func parseAff(r io.Reader) (*affixFile, error) {
+	af := &affixFile{affixes: make(map[string][]affix)}
+	cross := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "FLAG":
+			if len(fields) > 1 {
+				af.flagMode = fields[1]
+			}
+		case "TRY":
+			if len(fields) > 1 {
+				af.try = fields[1]
+			}
+		case "PFX", "SFX":
+			if len(fields) == 4 && (fields[2] == "Y" || fields[2] == "N") {
+				// Header: PFX flag cross_product count
+				cross[fields[1]] = fields[2] == "Y"
+				continue
+			}
+			if len(fields) < 5 {
+				continue
+			}
+
+			add, _, _ := strings.Cut(fields[3], "/")
+			if add == "0" {
+				add = ""
+			}
+			strip := fields[2]
+			if strip == "0" {
+				strip = ""
+			}
+
+			cond := fields[4]
+			var pattern string
+			if cond != "." {
+				if fields[0] == "PFX" {
+					pattern = "^" + cond
+				} else {
+					pattern = cond + "$"
+				}
+			}
+
+			a := affix{
+				flag:         fields[1],
+				prefix:       fields[0] == "PFX",
+				crossProduct: cross[fields[1]],
+				strip:        strip,
+				add:          add,
+			}
+			if pattern != "" {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					continue
+				}
+				a.condition = re
+			}
+			af.affixes[a.flag] = append(af.affixes[a.flag], a)
+		}
+	}
+	return af, scanner.Err()
+}
+
+func (af *affixFile) splitFlags(s string) []string {
+	switch af.flagMode {
+	case "long":
+		var flags []string
+		for i := 0; i+1 < len(s); i += 2 {
+			flags = append(flags, s[i:i+2])
+		}
+		return flags
+	case "num":
+		return strings.Split(s, ",")
+	default:
+		var flags []string
+		for _, r := range s {
+			flags = append(flags, string(r))
+		}
+		return flags
+	}
+}
+
+func (d *Dictionary) loadDic(r io.Reader, af *affixFile) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			first = false
+			if _, err := strconv.Atoi(line); err == nil {
+				continue
+			}
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Strip morphological fields ("word/FLAGS po:noun").
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+
+		word, flagStr, _ := strings.Cut(line, "/")
+		if word == "" {
+			continue
+		}
+		d.words[word] = struct{}{}
+		if flagStr == "" {
+			continue
+		}
+
+		var prefixes, suffixes []affix
+		for _, f := range af.splitFlags(flagStr) {
+			for _, a := range af.affixes[f] {
+				if a.prefix {
+					prefixes = append(prefixes, a)
+				} else {
+					suffixes = append(suffixes, a)
+				}
+			}
+		}
+
+		for _, s := range suffixes {
+			sw, ok := applyAffix(word, s)
+			if !ok {
+				continue
+			}
+			d.words[sw] = struct{}{}
+			if !s.crossProduct {
+				continue
+			}
+			for _, p := range prefixes {
+				if !p.crossProduct {
+					continue
+				}
+				if pw, ok := applyAffix(sw, p); ok {
+					d.words[pw] = struct{}{}
+				}
+			}
+		}
+		for _, p := range prefixes {
+			if pw, ok := applyAffix(word, p); ok {
+				d.words[pw] = struct{}{}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func applyAffix(word string, a affix) (string, bool) {
+	if a.condition != nil && !a.condition.MatchString(word) {
+		return "", false
+	}
+	if a.prefix {
+		if !strings.HasPrefix(word, a.strip) {
+			return "", false
+		}
+		return a.add + word[len(a.strip):], true
+	}
+	if !strings.HasSuffix(word, a.strip) {
+		return "", false
+	}
+	return word[:len(word)-len(a.strip)] + a.add, true
+}
+
+// Contains reports whether word is a known form. Capitalized and upper-case
+// variants of lower-case entries are accepted.
+func (d *Dictionary) Contains(word string) bool {
+	if _, ok := d.words[word]; ok {
+		return true
+	}
+	lower := strings.ToLower(word)
+	if _, ok := d.words[lower]; ok {
+		return true
+	}
+	// "Paris" stays valid when written "PARIS".
+	if word == strings.ToUpper(word) {
+		if _, ok := d.words[titleCase(lower)]; ok {
+			return true
+		}
+	}
+	return false
+}
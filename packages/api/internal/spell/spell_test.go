@@ -0,0 +1,111 @@
+package spell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAff = `SET UTF-8
+TRY esianrtolcdugmphbyfvkwzESIANRTOLCDUGMPHBYFVKWZ
+
+SFX S Y 2
+SFX S   y     ies        [^aeiou]y
+SFX S   0     s          [aeiou]y
+SFX S   0     s          [^y]
+
+PFX U Y 1
+PFX U   0     un         .
+`
+
+const testDic = `5
+hello
+world/S
+city/S
+do/U
+Paris
+`
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "en_US.aff"), []byte(testAff), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "en_US.dic"), []byte(testDic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewRegistry(dir)
+}
+
+func TestLoadHunspellExpandsAffixes(t *testing.T) {
+	d, err := testRegistry(t).Get("en-US")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, w := range []string{"hello", "Hello", "worlds", "cities", "undo", "Paris", "PARIS"} {
+		if !d.Contains(w) {
+			t.Errorf("expected %q to be known", w)
+		}
+	}
+	for _, w := range []string{"citys", "helo", "paris"} {
+		if d.Contains(w) {
+			t.Errorf("expected %q to be unknown", w)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	d, err := testRegistry(t).Get("en_US")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := "# Helo wrld\n\n```\nxyzzy\n```\n\nhello `qwerty` https://example.com/foo API custom\n"
+	got := Check(d, content, []string{"Custom"})
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 misspellings, got %+v", got)
+	}
+	if got[0].Word != "Helo" || got[0].Offset != 2 || got[0].Line != 1 || got[0].Column != 3 {
+		t.Errorf("unexpected first misspelling: %+v", got[0])
+	}
+	if !reflect.DeepEqual(got[0].Suggestions, []string{"Hello"}) {
+		t.Errorf("expected suggestion Hello, got %v", got[0].Suggestions)
+	}
+	if got[1].Word != "wrld" || got[1].Suggestions[0] != "world" {
+		t.Errorf("unexpected second misspelling: %+v", got[1])
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	d, err := testRegistry(t).Get("en_US")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	long := strings.Repeat("helo", 2500)
+	if got := Check(d, long, nil); len(got) != 1 || len(got[0].Suggestions) != 0 {
+		t.Errorf("expected no suggestions for a long word, got %+v", got)
+	}
+	if got := Check(d, strings.Repeat("wrld ", 2*maxMisspellings), nil); len(got) != maxMisspellings {
+		t.Errorf("expected %d misspellings, got %d", maxMisspellings, len(got))
+	}
+}
+
+func TestRegistryUnknownLanguage(t *testing.T) {
+	r := testRegistry(t)
+
+	if _, err := r.Get("de_DE"); err == nil {
+		t.Error("expected error for missing dictionary")
+	}
+	if _, err := r.Get("../etc/passwd"); err == nil {
+		t.Error("expected error for invalid language")
+	}
+	if langs := r.Languages(); !reflect.DeepEqual(langs, []string{"en_US"}) {
+		t.Errorf("expected [en_US], got %v", langs)
+	}
+}
@@ -0,0 +1,218 @@
+package tabular
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartWidth  = 640
+	chartHeight = 400
+	maxSeries   = 10
+)
+
+var palette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+type ChartOptions struct {
+	Type  string `json:"type"`            // "bar", "line" or "pie"
+	Theme string `json:"theme,omitempty"` // "light" (default) or "dark"
+	Title string `json:"title,omitempty"`
+}
+
+type series struct {
+	name   string
+	values []float64
+}
+
+// Chart renders t as an SVG chart. The first column holds the category labels
+// and every following column is a numeric series; pie charts use the first
+// series only.
+func (t *Table) Chart(opts ChartOptions) (string, error) {
+	var fg, grid string
+	switch opts.Theme {
+	case "", "light":
+		fg, grid = "#24292f", "#d0d7de"
+	case "dark":
+		fg, grid = "#e6edf3", "#30363d"
+	default:
+		return "", fmt.Errorf("invalid theme, must be 'dark' or 'light'")
+	}
+
+	labels, data, err := t.series()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	b.WriteString("\n")
+	if opts.Title != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="22" text-anchor="middle" font-size="15" font-weight="bold" fill="%s">%s</text>`+"\n", chartWidth/2, fg, html.EscapeString(opts.Title))
+	}
+
+	switch opts.Type {
+	case "bar", "line":
+		writeAxesChart(&b, opts.Type, labels, data, fg, grid)
+	case "pie":
+		if err := writePie(&b, labels, data[0], fg); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("invalid chart type, must be 'bar', 'line' or 'pie'")
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String(), nil
+}
+
+func (t *Table) series() ([]string, []series, error) {
+	if len(t.Header) < 2 {
+		return nil, nil, fmt.Errorf("%w: need a label column and at least one value column", ErrInvalidData)
+	}
+	if len(t.Rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: no data rows", ErrInvalidData)
+	}
+	if len(t.Header)-1 > maxSeries {
+		return nil, nil, fmt.Errorf("%w: %d series (max %d)", ErrInvalidData, len(t.Header)-1, maxSeries)
+	}
+
+	labels := make([]string, len(t.Rows))
+	data := make([]series, len(t.Header)-1)
+	for i := range data {
+		data[i] = series{name: t.Header[i+1], values: make([]float64, len(t.Rows))}
+	}
+	for r, row := range t.Rows {
+		labels[r] = row[0]
+		for i := range data {
+			cell := strings.TrimSpace(row[i+1])
+			if cell == "" {
+				continue
+			}
+			v, err := parseNumber(cell)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: row %d, column %q: %q is not a number", ErrInvalidData, r+2, data[i].name, cell)
+			}
+			data[i].values[r] = v
+		}
+	}
+	return labels, data, nil
+}
+
+func writeAxesChart(b *strings.Builder, kind string, labels []string, data []series, fg, grid string) {
+	const left, right, top, bottom = 60, 20, 50, 60
+	plotW := float64(chartWidth - left - right)
+	plotH := float64(chartHeight - top - bottom)
+
+	lo, hi := 0.0, 0.0
+	for _, s := range data {
+		for _, v := range s.values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	y := func(v float64) float64 { return top + plotH - (v-lo)/(hi-lo)*plotH }
+
+	// Grid lines and y labels
+	for i := 0; i <= 5; i++ {
+		v := lo + (hi-lo)*float64(i)/5
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="%s"/>`+"\n", left, y(v), chartWidth-right, y(v), grid)
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle" fill="%s">%s</text>`+"\n", left-6, y(v), fg, formatTick(v))
+	}
+
+	slot := plotW / float64(len(labels))
+	for i, label := range labels {
+		x := left + slot*(float64(i)+0.5)
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle" fill="%s">%s</text>`+"\n", x, chartHeight-bottom+18, fg, html.EscapeString(truncate(label, int(slot/7))))
+	}
+
+	for si, s := range data {
+		color := palette[si%len(palette)]
+		if kind == "bar" {
+			barW := slot * 0.8 / float64(len(data))
+			for i, v := range s.values {
+				x := left + slot*float64(i) + slot*0.1 + barW*float64(si)
+				y0, y1 := y(math.Max(v, 0)), y(math.Min(v, 0))
+				fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`+"\n",
+					x, y0, barW, y1-y0, color, html.EscapeString(s.name), formatTick(v))
+			}
+			continue
+		}
+
+		points := make([]string, len(s.values))
+		for i, v := range s.values {
+			points[i] = fmt.Sprintf("%.1f,%.1f", left+slot*(float64(i)+0.5), y(v))
+		}
+		fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), color)
+		for _, p := range points {
+			cx, cy, _ := strings.Cut(p, ",")
+			fmt.Fprintf(b, `<circle cx="%s" cy="%s" r="3" fill="%s"/>`+"\n", cx, cy, color)
+		}
+	}
+
+	if len(data) > 1 {
+		for si, s := range data {
+			x := left + si*100
+			fmt.Fprintf(b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`+"\n", x, chartHeight-22, palette[si%len(palette)])
+			fmt.Fprintf(b, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", x+14, chartHeight-13, fg, html.EscapeString(truncate(s.name, 12)))
+		}
+	}
+}
+
+func writePie(b *strings.Builder, labels []string, s series, fg string) error {
+	total := 0.0
+	for _, v := range s.values {
+		if v < 0 {
+			return fmt.Errorf("%w: pie charts can't show negative values", ErrInvalidData)
+		}
+		total += v
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: pie chart values sum to zero", ErrInvalidData)
+	}
+
+	const cx, cy, r = 220.0, 215.0, 150.0
+	angle := -math.Pi / 2
+	for i, v := range s.values {
+		color := palette[i%len(palette)]
+		frac := v / total
+		title := fmt.Sprintf("%s: %s", html.EscapeString(labels[i]), formatTick(v))
+
+		if frac >= 1 {
+			fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"><title>%s</title></circle>`+"\n", cx, cy, r, color, title)
+		} else if frac > 0 {
+			end := angle + frac*2*math.Pi
+			large := 0
+			if frac > 0.5 {
+				large = 1
+			}
+			fmt.Fprintf(b, `<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d 1 %.1f,%.1f Z" fill="%s"><title>%s</title></path>`+"\n",
+				cx, cy, cx+r*math.Cos(angle), cy+r*math.Sin(angle), r, r, large, cx+r*math.Cos(end), cy+r*math.Sin(end), color, title)
+			angle = end
+		}
+
+		if i < 15 {
+			ly := 70 + i*20
+			fmt.Fprintf(b, `<rect x="400" y="%d" width="10" height="10" fill="%s"/>`+"\n", ly, color)
+			fmt.Fprintf(b, `<text x="416" y="%d" fill="%s">%s (%.1f%%)</text>`+"\n", ly+9, fg, html.EscapeString(truncate(labels[i], 24)), frac*100)
+		}
+	}
+	return nil
+}
+
+func formatTick(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+func truncate(s string, n int) string {
+	n = max(n, 2)
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,100 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxRows    = 1000
+	MaxColumns = 50
+)
+
+var ErrInvalidData = errors.New("invalid data")
+
+// Table is parsed CSV/TSV data. The first record is the header.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Parse reads CSV or TSV content. delimiter may be "", ",", ";" or "\t";
+// when empty, TSV is assumed if the first line contains a tab.
+func Parse(content, delimiter string) (*Table, error) {
+	comma := ','
+	switch delimiter {
+	case "":
+		first, _, _ := strings.Cut(content, "\n")
+		if strings.Contains(first, "\t") {
+			comma = '\t'
+		}
+	case ",", ";", "\t":
+		comma = rune(delimiter[0])
+	default:
+		return nil, fmt.Errorf("invalid delimiter, must be ',', ';' or tab")
+	}
+
+	cr := csv.NewReader(strings.NewReader(strings.TrimSpace(content)))
+	cr.Comma = comma
+	cr.TrimLeadingSpace = true
+	cr.LazyQuotes = comma == '\t'
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidData)
+	}
+	if len(records)-1 > MaxRows {
+		return nil, fmt.Errorf("%w: %d rows (max %d)", ErrInvalidData, len(records)-1, MaxRows)
+	}
+	if len(records[0]) > MaxColumns {
+		return nil, fmt.Errorf("%w: %d columns (max %d)", ErrInvalidData, len(records[0]), MaxColumns)
+	}
+
+	return &Table{Header: records[0], Rows: records[1:]}, nil
+}
+
+// numericColumn reports whether every non-empty cell in column i is a number.
+func (t *Table) numericColumn(i int) bool {
+	seen := false
+	for _, row := range t.Rows {
+		cell := strings.TrimSpace(row[i])
+		if cell == "" {
+			continue
+		}
+		if _, err := parseNumber(cell); err != nil {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func (t *Table) numericColumns() []bool {
+	numeric := make([]bool, len(t.Header))
+	for i := range t.Header {
+		numeric[i] = t.numericColumn(i)
+	}
+	return numeric
+}
+
+// parseNumber accepts plain numbers plus thousands separators and a trailing
+// percent sign ("1,200", "45%"). NaN and infinities are text, since they
+// can't be scaled onto a chart.
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), "%")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("not a finite number: %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package tabular
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markdown renders t as a GFM table with padded columns. Numeric columns are
+// right-aligned.
+func (t *Table) Markdown() string {
+	widths := make([]int, len(t.Header))
+	for i, h := range t.Header {
+		widths[i] = max(3, utf8.RuneCountInString(escapeCell(h)))
+	}
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(escapeCell(cell)))
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string, right []bool) {
+		b.WriteString("|")
+		for i, cell := range cells {
+			cell = escapeCell(cell)
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if right != nil && right[i] {
+				b.WriteString(" " + pad + cell + " |")
+			} else {
+				b.WriteString(" " + cell + pad + " |")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	right := t.numericColumns()
+
+	writeRow(t.Header, nil)
+	b.WriteString("|")
+	for i, w := range widths {
+		if right[i] {
+			b.WriteString(" " + strings.Repeat("-", w-1) + ": |")
+		} else {
+			b.WriteString(" " + strings.Repeat("-", w) + " |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range t.Rows {
+		writeRow(row, right)
+	}
+	return b.String()
+}
+
+// HTML renders t as an HTML table. All cell content is escaped.
+func (t *Table) HTML() string {
+	right := t.numericColumns()
+
+	var b strings.Builder
+	b.WriteString("<table>\n<thead>\n<tr>")
+	for _, h := range t.Header {
+		b.WriteString("<th>" + html.EscapeString(h) + "</th>")
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range t.Rows {
+		b.WriteString("<tr>")
+		for i, cell := range row {
+			if right[i] {
+				b.WriteString(`<td align="right">` + html.EscapeString(cell) + "</td>")
+			} else {
+				b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+			}
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return b.String()
+}
+
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,98 @@
+package tabular
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseDetectsTSV(t *testing.T) {
+	tbl, err := Parse("name\tcount\nfoo\t1\nbar\t2\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbl.Header) != 2 || len(tbl.Rows) != 2 || tbl.Rows[1][1] != "2" {
+		t.Errorf("unexpected table: %+v", tbl)
+	}
+}
+
+func TestParseRaggedRows(t *testing.T) {
+	if _, err := Parse("a,b\n1,2,3\n", ""); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("expected ErrInvalidData, got %v", err)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tbl, err := Parse("name,count\nfoo|bar,1200\nbaz,\"3,5\"\n", ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "| name     | count |\n" +
+		"| -------- | ----: |\n" +
+		"| foo\\|bar |  1200 |\n" +
+		"| baz      |   3,5 |\n"
+	if got := tbl.Markdown(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHTMLEscapes(t *testing.T) {
+	tbl, err := Parse("a\n<script>\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tbl.HTML(); strings.Contains(got, "<script>") {
+		t.Errorf("cell not escaped: %s", got)
+	}
+}
+
+func TestChart(t *testing.T) {
+	tbl, err := Parse("month,sales,costs\nJan,10,4\nFeb,15,6\nMar,-2,3\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typ := range []string{"bar", "line"} {
+		svg, err := tbl.Chart(ChartOptions{Type: typ, Title: "Q1 <draft>"})
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "Q1 &lt;draft&gt;") {
+			t.Errorf("%s: unexpected output: %s", typ, svg)
+		}
+	}
+
+	if _, err := tbl.Chart(ChartOptions{Type: "pie"}); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("expected negative pie value to fail, got %v", err)
+	}
+	if _, err := tbl.Chart(ChartOptions{Type: "radar"}); err == nil {
+		t.Error("expected unknown chart type to fail")
+	}
+}
+
+func TestChartPie(t *testing.T) {
+	tbl, err := Parse("lang,share\nGo,3\nRust,1\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg, err := tbl.Chart(ChartOptions{Type: "pie", Theme: "dark"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(svg, "<path") != 2 || !strings.Contains(svg, "75.0%") {
+		t.Errorf("unexpected pie: %s", svg)
+	}
+}
+
+func TestChartNonNumeric(t *testing.T) {
+	for _, csv := range []string{"a,b\nx,y\n", "a,b\nx,NaN\ny,Inf\nz,-infinity\n"} {
+		tbl, err := Parse(csv, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tbl.Chart(ChartOptions{Type: "bar"}); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%q: expected ErrInvalidData, got %v", csv, err)
+		}
+	}
+}
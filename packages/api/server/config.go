@@ -0,0 +1,221 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures an embedded or standalone server. Zero values fall back
+// to the defaults listed on each field.
+type Config struct {
+	Addr      string     // listen address for Serve, default ":8080"
+	Listeners []Listener // replaces Addr when set
+
+	RenderTimeout    time.Duration // per-render timeout, default 10s
+	RenderRateLimit  int           // renders per minute per IP, default 60; negative disables
+	RenderTokens     []string      // bearer tokens required for render routes, none by default
+	RenderSigningKey []byte        // HMAC key for signed render URLs, unsigned by default
+	MermaidVersions  []string      // extra mermaid releases to load next to the default
+
+	// RenderQuota is the daily request budget per render token, unlimited by
+	// default; RenderQuotaOverrides sets it per token (0 = unlimited).
+	RenderQuota          int
+	RenderQuotaOverrides map[string]int
+
+	// Request timeouts per route group: cheap reads (health, versions, share
+	// fetches) fail fast, renders get room for large diagrams, everything else
+	// uses DefaultTimeout. Defaults 5s, 60s and 30s.
+	ReadTimeout        time.Duration
+	RenderRouteTimeout time.Duration
+	DefaultTimeout     time.Duration
+
+	// MaintenanceFile turns maintenance mode on while it exists. Write routes
+	// (share create/delete) return 503 during maintenance; with
+	// MaintenanceBlockReads every route except /health, /metrics and
+	// /admin/maintenance does.
+	MaintenanceFile       string
+	MaintenanceBlockReads bool
+
+	// Client address rules, applied before auth: IPRules to every route,
+	// RenderIPRules to /render and AdminIPRules to /admin.
+	IPRules       IPRules
+	RenderIPRules IPRules
+	AdminIPRules  IPRules
+
+	// ReadySaturation is how long a renderer may have requests queued before
+	// /readyz fails, default 30s.
+	ReadySaturation time.Duration
+
+	// TrustedProxies are the networks (CIDRs or bare IPs) whose
+	// X-Forwarded-For and X-Request-ID headers are believed, default
+	// loopback only.
+	TrustedProxies []string
+
+	DictionaryDir string // hunspell dictionaries, default /usr/share/hunspell
+	ShareDir      string // anonymous share storage, default data/shares
+	// PublicURL (e.g. https://md.example.com) is used to build share links.
+	// Without it they are built from the request's Host and, from trusted
+	// proxies only, X-Forwarded-Proto.
+	PublicURL string
+	// Shared pages drop raw HTML unless ShareHTMLTags lists elements to keep
+	// or ShareIframeHosts hosts to embed; ShareImageHosts restricts images.
+	ShareHTMLTags    []string
+	ShareIframeHosts []string
+	ShareImageHosts  []string
+	// SharePrimeRenders pre-renders the diagrams in new shares into the
+	// render cache in the background.
+	SharePrimeRenders bool
+	// SecretScan is what share creation does with likely credentials:
+	// "off", "warn" (default, reported in the response) or "block".
+	SecretScan string
+}
+
+// IPRules lists CIDRs or bare IPs to allow or deny. Deny wins; a non-empty
+// Allow refuses everything not in it.
+type IPRules struct {
+	Allow []string
+	Deny  []string
+}
+
+// ConfigFromEnv reads the configuration from the environment variables
+// documented in the README.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		DictionaryDir:    os.Getenv("DICTIONARY_DIR"),
+		ShareDir:         os.Getenv("SHARE_DIR"),
+		PublicURL:        os.Getenv("PUBLIC_URL"),
+		SecretScan:       os.Getenv("SECRET_SCAN"),
+		ShareHTMLTags:    envList("SHARE_HTML_TAGS"),
+		ShareIframeHosts: envList("SHARE_IFRAME_HOSTS"),
+		ShareImageHosts:  envList("SHARE_IMAGE_HOSTS"),
+		RenderSigningKey: []byte(os.Getenv("RENDER_SIGNING_KEY")),
+		MermaidVersions:  envList("MERMAID_VERSIONS"),
+		RenderTokens:     envList("RENDER_TOKENS"),
+		TrustedProxies:   envList("TRUSTED_PROXIES"),
+		MaintenanceFile:  os.Getenv("MAINTENANCE_FILE"),
+		IPRules:          envIPRules(""),
+		RenderIPRules:    envIPRules("RENDER_"),
+		AdminIPRules:     envIPRules("ADMIN_"),
+
+		MaintenanceBlockReads: os.Getenv("MAINTENANCE_BLOCK_READS") == "true",
+		SharePrimeRenders:     os.Getenv("SHARE_PRIME_RENDERS") == "true",
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Addr = ":" + port
+	}
+
+	var err error
+	if cfg.Listeners, err = ParseListeners(os.Getenv("LISTEN")); err != nil {
+		return cfg, err
+	}
+	if cfg.RenderTimeout, err = envDuration("RENDER_TIMEOUT"); err != nil {
+		return cfg, err
+	}
+	if cfg.ReadTimeout, err = envDuration("ROUTE_TIMEOUT_READ"); err != nil {
+		return cfg, err
+	}
+	if cfg.RenderRouteTimeout, err = envDuration("ROUTE_TIMEOUT_RENDER"); err != nil {
+		return cfg, err
+	}
+	if cfg.DefaultTimeout, err = envDuration("ROUTE_TIMEOUT_DEFAULT"); err != nil {
+		return cfg, err
+	}
+	if cfg.RenderQuota, err = envInt("RENDER_QUOTA"); err != nil {
+		return cfg, err
+	}
+	if cfg.RenderQuotaOverrides, err = envQuotas("RENDER_QUOTA_OVERRIDES"); err != nil {
+		return cfg, err
+	}
+	if cfg.ReadySaturation, err = envDuration("READY_SATURATION"); err != nil {
+		return cfg, err
+	}
+	if cfg.RenderRateLimit, err = envInt("RENDER_RATE_LIMIT"); err != nil {
+		return cfg, err
+	}
+	// RENDER_RATE_LIMIT=0 disables the limit, unlike the zero value.
+	if v := os.Getenv("RENDER_RATE_LIMIT"); v != "" && cfg.RenderRateLimit == 0 {
+		cfg.RenderRateLimit = -1
+	}
+	return cfg, nil
+}
+
+func (c Config) withDefaults() Config {
+	if c.Addr == "" {
+		c.Addr = ":8080"
+	}
+	if c.RenderRateLimit == 0 {
+		c.RenderRateLimit = 60
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 5 * time.Second
+	}
+	if c.RenderRouteTimeout == 0 {
+		c.RenderRouteTimeout = 60 * time.Second
+	}
+	if c.DefaultTimeout == 0 {
+		c.DefaultTimeout = 30 * time.Second
+	}
+	if c.TrustedProxies == nil {
+		c.TrustedProxies = []string{"127.0.0.0/8", "::1"}
+	}
+	if c.DictionaryDir == "" {
+		c.DictionaryDir = "/usr/share/hunspell"
+	}
+	if c.ShareDir == "" {
+		c.ShareDir = "data/shares"
+	}
+	return c
+}
+
+func envList(key string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func envIPRules(prefix string) IPRules {
+	return IPRules{Allow: envList(prefix + "IP_ALLOW"), Deny: envList(prefix + "IP_DENY")}
+}
+
+// envQuotas parses "token=limit" pairs.
+func envQuotas(key string) (map[string]int, error) {
+	quotas := make(map[string]int)
+	for _, pair := range envList(key) {
+		token, limit, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(limit)
+		if !ok || token == "" || err != nil {
+			return nil, fmt.Errorf("invalid %s entry, must be token=limit", key)
+		}
+		quotas[token] = n
+	}
+	return quotas, nil
+}
+
+func envInt(key string) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+func envDuration(key string) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
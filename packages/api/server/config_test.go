@@ -0,0 +1,46 @@
+package server
+
+import "testing"
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("PORT", "9000")
+	t.Setenv("RENDER_TOKENS", "a,b")
+	t.Setenv("RENDER_RATE_LIMIT", "0")
+	t.Setenv("RENDER_TIMEOUT", "5s")
+	t.Setenv("ROUTE_TIMEOUT_RENDER", "2m")
+	t.Setenv("RENDER_QUOTA", "500")
+	t.Setenv("RENDER_QUOTA_OVERRIDES", "a=5000,b=0")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = cfg.withDefaults()
+
+	if cfg.Addr != ":9000" || len(cfg.RenderTokens) != 2 || cfg.RenderTimeout.Seconds() != 5 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg.RenderRateLimit > 0 {
+		t.Errorf("expected RENDER_RATE_LIMIT=0 to disable the limit, got %d", cfg.RenderRateLimit)
+	}
+	if cfg.RenderRouteTimeout.Minutes() != 2 || cfg.ReadTimeout.Seconds() != 5 {
+		t.Errorf("unexpected route timeouts: read %s, render %s", cfg.ReadTimeout, cfg.RenderRouteTimeout)
+	}
+	if cfg.RenderQuota != 500 || cfg.RenderQuotaOverrides["a"] != 5000 || len(cfg.RenderQuotaOverrides) != 2 {
+		t.Errorf("unexpected quotas: %d %v", cfg.RenderQuota, cfg.RenderQuotaOverrides)
+	}
+	if cfg.ShareDir != "data/shares" {
+		t.Errorf("expected default share dir, got %q", cfg.ShareDir)
+	}
+
+	t.Setenv("RENDER_QUOTA_OVERRIDES", "a")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected invalid RENDER_QUOTA_OVERRIDES to fail")
+	}
+	t.Setenv("RENDER_QUOTA_OVERRIDES", "")
+
+	t.Setenv("RENDER_TIMEOUT", "soon")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected invalid RENDER_TIMEOUT to fail")
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Listener is one address Serve listens on.
+type Listener struct {
+	Network string // "tcp", "unix" or "systemd"
+	// Addr is a host:port for tcp, a socket path for unix, and an optional
+	// LISTEN_FDNAMES name for systemd (empty takes the first passed socket).
+	Addr string
+	// Trusted listeners get TrustedHandler: no render tokens, signed URLs or
+	// rate limits. Only use it for sockets the public can't reach.
+	Trusted bool
+}
+
+func (l Listener) String() string {
+	s := l.Network + ":" + l.Addr
+	if l.Trusted {
+		s = "trusted+" + s
+	}
+	return s
+}
+
+// ParseListeners parses a comma-separated LISTEN value. Each entry is an
+// address (":8080", "tcp:127.0.0.1:8080"), a unix socket ("unix:/run/md.sock")
+// or a systemd-activated socket ("systemd:" or "systemd:name"), optionally
+// prefixed with "trusted+".
+func ParseListeners(v string) ([]Listener, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	var listeners []Listener
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		var l Listener
+		entry, l.Trusted = strings.CutPrefix(entry, "trusted+")
+
+		switch {
+		case strings.HasPrefix(entry, "unix:"):
+			l.Network, l.Addr = "unix", strings.TrimPrefix(entry, "unix:")
+			if l.Addr == "" {
+				return nil, fmt.Errorf("invalid LISTEN entry %q: missing socket path", entry)
+			}
+		case strings.HasPrefix(entry, "systemd:"):
+			l.Network, l.Addr = "systemd", strings.TrimPrefix(entry, "systemd:")
+		default:
+			l.Network, l.Addr = "tcp", strings.TrimPrefix(entry, "tcp:")
+			if _, _, err := net.SplitHostPort(l.Addr); err != nil {
+				return nil, fmt.Errorf("invalid LISTEN entry %q: %w", entry, err)
+			}
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func (l Listener) listen() (net.Listener, error) {
+	switch l.Network {
+	case "unix":
+		// A socket file left over from an unclean shutdown blocks Listen.
+		// Anything else at the path is left alone for Listen to fail on.
+		if fi, err := os.Lstat(l.Addr); err == nil && fi.Mode().Type() == os.ModeSocket {
+			if err := os.Remove(l.Addr); err != nil {
+				return nil, err
+			}
+		}
+		ln, err := net.Listen("unix", l.Addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(l.Addr, 0o660); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	case "systemd":
+		return systemdListener(l.Addr)
+	default:
+		return net.Listen("tcp", l.Addr)
+	}
+}
+
+// systemdListener returns a socket passed by systemd socket activation
+// (LISTEN_PID/LISTEN_FDS/LISTEN_FDNAMES, fds starting at 3).
+func systemdListener(name string) (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("systemd: no sockets passed to this process")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("systemd: no sockets passed to this process")
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		if name != "" && (i >= len(names) || names[i] != name) {
+			continue
+		}
+		f := os.NewFile(uintptr(3+i), "systemd-socket-"+strconv.Itoa(i))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("systemd: %w", err)
+		}
+		return ln, nil
+	}
+	return nil, fmt.Errorf("systemd: no socket named %q", name)
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseListeners(t *testing.T) {
+	got, err := ParseListeners(":8080, trusted+unix:/run/md/md.sock,systemd:web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Listener{
+		{Network: "tcp", Addr: ":8080"},
+		{Network: "unix", Addr: "/run/md/md.sock", Trusted: true},
+		{Network: "systemd", Addr: "web"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d listeners, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listener %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	for _, v := range []string{"8080", "unix:"} {
+		if _, err := ParseListeners(v); err == nil {
+			t.Errorf("expected %q to be rejected", v)
+		}
+	}
+}
+
+func TestUnixListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "md.sock")
+	l := Listener{Network: "unix", Addr: path}
+
+	ln, err := l.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+
+	// A stale socket file must not block the next start.
+	ln, err = l.listen()
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced: %v", err)
+	}
+	ln.Close()
+
+	file := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(file, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err := (Listener{Network: "unix", Addr: file}).listen(); err == nil {
+		ln.Close()
+		t.Error("expected listen on a regular file to fail")
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "keep" {
+		t.Errorf("expected regular file to be left alone, got %q, %v", data, err)
+	}
+}
+
+func TestTrustedRoutesSkipAuth(t *testing.T) {
+	s, err := newServer(Config{RenderTokens: []string{"secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		trusted bool
+		want    int
+	}{
+		{false, http.StatusUnauthorized},
+		{true, http.StatusBadRequest}, // reaches the handler, which rejects the hash
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/render/ascii/abc123?code=e30", nil)
+		w := httptest.NewRecorder()
+
+		h := s.Handler()
+		if tt.trusted {
+			h = s.TrustedHandler()
+		}
+		h.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("trusted=%v: expected status %d, got %d", tt.trusted, tt.want, w.Code)
+		}
+	}
+}
+
+func TestMaintenanceToggle(t *testing.T) {
+	s, err := newServer(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, trusted := s.Handler(), s.TrustedHandler()
+
+	serve := func(h http.Handler, method, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	if code := serve(public, http.MethodPut, "/admin/maintenance"); code == http.StatusOK {
+		t.Fatal("admin route must not be reachable on public listeners")
+	}
+	if code := serve(trusted, http.MethodPut, "/admin/maintenance"); code != http.StatusOK {
+		t.Fatalf("expected 200 enabling maintenance, got %d", code)
+	}
+	if code := serve(public, http.MethodDelete, "/share/abc"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected share delete to be refused, got %d", code)
+	}
+	if code := serve(public, http.MethodGet, "/health"); code != http.StatusOK {
+		t.Errorf("expected health to be served, got %d", code)
+	}
+
+	s.SetMaintenance(false)
+	if code := serve(public, http.MethodDelete, "/share/abc"); code == http.StatusServiceUnavailable {
+		t.Error("expected maintenance to be off")
+	}
+
+	s, err = newServer(Config{MaintenanceBlockReads: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetMaintenance(true)
+	if code := serve(s.Handler(), http.MethodGet, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz to fail while reads are blocked, got %d", code)
+	}
+	if code := serve(s.Handler(), http.MethodGet, "/metrics"); code != http.StatusOK {
+		t.Errorf("expected metrics to be served, got %d", code)
+	}
+}
+
+func TestRenderIPRules(t *testing.T) {
+	s, err := newServer(Config{RenderIPRules: IPRules{Allow: []string{"10.0.0.0/8"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.Handler()
+
+	for _, tt := range []struct {
+		remote, path string
+		want         int
+	}{
+		{"203.0.113.5:1234", "/render/ascii/abc123", http.StatusForbidden},
+		{"10.1.2.3:1234", "/render/ascii/abc123", http.StatusBadRequest},
+		{"203.0.113.5:1234", "/health", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = tt.remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.remote, tt.path, tt.want, w.Code)
+		}
+	}
+
+	if _, err := newServer(Config{AdminIPRules: IPRules{Deny: []string{"nope"}}}); err == nil {
+		t.Error("expected invalid rule to fail")
+	}
+}
+
+func TestQuotaNeedsTokens(t *testing.T) {
+	if _, err := newServer(Config{RenderQuota: 100}); err == nil {
+		t.Error("expected a quota without render tokens to fail")
+	}
+	if _, err := newServer(Config{RenderQuota: 100, RenderTokens: []string{"secret"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+// Package server exposes the md rendering API as an embeddable http.Handler,
+// so other Go programs can mount it under their own router instead of
+// running the standalone binary.
+//
+// The handlers keep renderers, dictionaries and the share store in package
+// state, so only one Server may exist per process.
+package server
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dnl-fm/md/packages/api/internal/handlers"
+	apimiddleware "github.com/dnl-fm/md/packages/api/internal/middleware"
+	"github.com/dnl-fm/md/packages/api/internal/renderer"
+	"github.com/dnl-fm/md/packages/api/internal/secrets"
+	"github.com/dnl-fm/md/packages/api/internal/share"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/go-chi/httprate"
+)
+
+// Server owns the headless browser, dictionaries and share store behind the
+// API routes.
+type Server struct {
+	cfg         Config
+	proxies     []*net.IPNet
+	maintenance *apimiddleware.Maintenance
+	quota       *apimiddleware.Quota
+	ipRules     map[string]ipNets
+	handler     http.Handler
+	trusted     http.Handler
+	stopJanitor chan struct{}
+}
+
+// New starts the renderers and loads dictionaries and shares. Call Close
+// when done to shut the browser down.
+func New(cfg Config) (*Server, error) {
+	s, err := newServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg = s.cfg
+
+	secretPolicy, err := secrets.ParsePolicy(cfg.SecretScan)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := renderer.DefaultLimits
+	if cfg.RenderTimeout > 0 {
+		limits.Timeout = cfg.RenderTimeout
+	}
+
+	log.Println("Initializing renderers...")
+	if err := handlers.InitializeRenderers(limits, cfg.MermaidVersions); err != nil {
+		handlers.CloseRenderers()
+		return nil, fmt.Errorf("failed to initialize renderers: %w", err)
+	}
+	log.Println("Renderers ready")
+
+	handlers.InitializeReadiness(cfg.ReadySaturation)
+	handlers.InitializeRenderPriming(cfg.SharePrimeRenders)
+	handlers.InitializeDictionaries(cfg.DictionaryDir)
+	handlers.InitializePublicURL(cfg.PublicURL)
+
+	if err := handlers.InitializeShares(cfg.ShareDir, share.Policy{
+		Tags:        cfg.ShareHTMLTags,
+		IframeHosts: cfg.ShareIframeHosts,
+		ImageHosts:  cfg.ShareImageHosts,
+	}, secretPolicy); err != nil {
+		handlers.CloseRenderers()
+		return nil, err
+	}
+
+	handlers.StartShareJanitor(time.Hour, s.stopJanitor)
+	return s, nil
+}
+
+// newServer parses cfg and builds the routes, without touching the handlers'
+// package state.
+func newServer(cfg Config) (*Server, error) {
+	cfg = cfg.withDefaults()
+
+	proxies, err := apimiddleware.ParseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	ipRules, err := parseIPRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PublicURL != "" {
+		u, err := url.Parse(cfg.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid public URL %q", cfg.PublicURL)
+		}
+	}
+	if (cfg.RenderQuota > 0 || len(cfg.RenderQuotaOverrides) > 0) && len(cfg.RenderTokens) == 0 {
+		return nil, fmt.Errorf("render quotas need render tokens: any client could claim a fresh budget")
+	}
+
+	s := &Server{
+		cfg:         cfg,
+		proxies:     proxies,
+		ipRules:     ipRules,
+		maintenance: apimiddleware.NewMaintenance(cfg.MaintenanceFile, cfg.MaintenanceBlockReads, 0),
+		quota:       apimiddleware.NewQuota(cfg.RenderQuota, cfg.RenderQuotaOverrides),
+		stopJanitor: make(chan struct{}),
+	}
+	s.handler = s.routes(false)
+	s.trusted = s.routes(true)
+	return s, nil
+}
+
+// Handler returns the API as an http.Handler. Routes are absolute
+// (/render/..., /share/...); mount it with http.StripPrefix or chi's Mount
+// to serve it under a prefix.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// TrustedHandler is Handler without render tokens, signed URLs and rate
+// limits, for listeners only local tooling can reach.
+func (s *Server) TrustedHandler() http.Handler {
+	return s.trusted
+}
+
+// SetMaintenance turns maintenance mode on or off, e.g. around a backup of
+// ShareDir.
+func (s *Server) SetMaintenance(on bool) {
+	s.maintenance.Set(on)
+}
+
+// Close stops background jobs and the headless browser.
+func (s *Server) Close() {
+	close(s.stopJanitor)
+	handlers.CloseRenderers()
+}
+
+// Serve runs a standalone server on cfg.Listeners (or cfg.Addr when there
+// are none) until one of them fails.
+func Serve(cfg Config) error {
+	s, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	listeners := s.cfg.Listeners
+	if len(listeners) == 0 {
+		listeners = []Listener{{Network: "tcp", Addr: s.cfg.Addr}}
+	}
+
+	errc := make(chan error, len(listeners))
+	for _, l := range listeners {
+		ln, err := l.listen()
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+
+		handler := s.Handler()
+		if l.Trusted {
+			handler = s.TrustedHandler()
+		}
+		log.Printf("Starting server on %s", l)
+		go func() {
+			errc <- http.Serve(ln, handler)
+		}()
+	}
+	return <-errc
+}
+
+func (s *Server) routes(trusted bool) http.Handler {
+	r := chi.NewRouter()
+
+	// Middleware
+	r.Use(apimiddleware.RequestID(s.proxies))
+	r.Use(apimiddleware.RealIP(s.proxies))
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(s.ipFilter("global"))
+	r.Use(apimiddleware.DecompressBody(maxDecodedBody))
+
+	// CORS
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Delete-Token", "Content-Encoding", "Range", "If-Range"},
+		ExposedHeaders:   []string{"X-Cache-Status", "X-Render-Duration", "Age", "X-SVG-Width", "X-SVG-Height", "X-Request-ID", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset", "Content-Range", "ETag"},
+		AllowCredentials: false,
+		MaxAge:           300,
+	}))
+
+	// Routes, grouped by how long they may take
+	r.With(middleware.Timeout(s.cfg.ReadTimeout)).Get("/health", handlers.Health)
+	// /readyz fails with the other reads during maintenance, so load
+	// balancers drain the instance.
+	r.With(middleware.Timeout(s.cfg.ReadTimeout), s.maintenance.Guard(false)).Get("/readyz", handlers.Ready)
+	r.With(middleware.Timeout(s.cfg.ReadTimeout), s.ipFilter("admin")).Get("/metrics", handlers.Metrics)
+	if trusted {
+		r.Group(func(r chi.Router) {
+			r.Use(s.ipFilter("admin"))
+			r.Get("/admin/maintenance", s.maintenance.Toggle)
+			r.Put("/admin/maintenance", s.maintenance.Toggle)
+			r.Delete("/admin/maintenance", s.maintenance.Toggle)
+		})
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(s.cfg.ReadTimeout), s.maintenance.Guard(false))
+		r.Get("/render/versions", handlers.RenderVersions)
+		r.Get("/check/spelling/languages", handlers.SpellingLanguages)
+		r.Get("/share/{id}", handlers.GetShare)
+		r.Get("/share/{id}/raw", handlers.GetShareRaw)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(s.cfg.RenderRouteTimeout), s.ipFilter("render"), s.maintenance.Guard(false))
+		if !trusted {
+			r.Use(apimiddleware.RequireToken(s.cfg.RenderTokens))
+			r.Use(s.quota.Handler)
+			if s.cfg.RenderRateLimit > 0 {
+				r.Use(httprate.LimitByIP(s.cfg.RenderRateLimit, time.Minute))
+			}
+		}
+
+		r.Post("/render/mermaid/validate", handlers.ValidateMermaid)
+		r.Post("/render/table", handlers.RenderTable)
+		r.Post("/render/chart", handlers.RenderChart)
+		r.Group(func(r chi.Router) {
+			if !trusted {
+				r.Use(apimiddleware.SignedURL(s.cfg.RenderSigningKey))
+			}
+			r.Get("/render/mermaid/{theme}/{hash}", handlers.RenderMermaid)
+			r.Get("/render/v/{rendererVersion}/mermaid/{theme}/{hash}", handlers.RenderMermaidVersioned)
+			r.Get("/render/ascii/{hash}", handlers.RenderASCII)
+			r.Get("/render/vegalite/{theme}/{hash}", handlers.RenderVegaLite)
+			r.Get("/render/wavedrom/{hash}", handlers.RenderWaveDrom)
+			r.Get("/render/nomnoml/{hash}", handlers.RenderNomnoml)
+		})
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(s.cfg.DefaultTimeout))
+		r.Group(func(r chi.Router) {
+			r.Use(s.maintenance.Guard(false))
+			if trusted {
+				r.Get("/proxy/image", handlers.ProxyImage)
+			} else {
+				r.With(httprate.LimitByIP(120, time.Minute)).Get("/proxy/image", handlers.ProxyImage)
+			}
+			r.Post("/lint/markdown", handlers.LintMarkdown)
+			r.Post("/format/markdown", handlers.FormatMarkdown)
+			r.Post("/check/spelling", handlers.CheckSpelling)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(s.maintenance.Guard(true))
+			if trusted {
+				r.Post("/share", handlers.CreateShare)
+			} else {
+				r.With(httprate.LimitByIP(20, time.Hour)).Post("/share", handlers.CreateShare)
+			}
+			r.Delete("/share/{id}", handlers.DeleteShare)
+		})
+	})
+
+	return r
+}
+
+// maxDecodedBody caps compressed request bodies once decoded. It matches
+// the handlers' own limit on JSON bodies.
+const maxDecodedBody = 1 << 20
+
+type ipNets struct {
+	allow, deny []*net.IPNet
+}
+
+func parseIPRules(cfg Config) (map[string]ipNets, error) {
+	rules := make(map[string]ipNets)
+	for name, r := range map[string]IPRules{
+		"global": cfg.IPRules,
+		"render": cfg.RenderIPRules,
+		"admin":  cfg.AdminIPRules,
+	} {
+		var n ipNets
+		var err error
+		if n.allow, err = apimiddleware.ParseNetworks(r.Allow); err != nil {
+			return nil, fmt.Errorf("invalid %s IP allowlist: %w", name, err)
+		}
+		if n.deny, err = apimiddleware.ParseNetworks(r.Deny); err != nil {
+			return nil, fmt.Errorf("invalid %s IP denylist: %w", name, err)
+		}
+		rules[name] = n
+	}
+	return rules, nil
+}
+
+func (s *Server) ipFilter(name string) func(http.Handler) http.Handler {
+	n := s.ipRules[name]
+	return apimiddleware.IPFilter(name, n.allow, n.deny)
+}